@@ -0,0 +1,160 @@
+package invoke
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Constraint checks the value of a typed path segment such as {id:int} and
+// returns it converted, e.g., as an int64. It reports false if the value does
+// not satisfy the constraint, in which case the segment does not match.
+type Constraint func(value string) (interface{}, bool)
+
+// ConstraintFactory builds a constraint from the arguments written in the
+// route pattern, e.g., ["1", "100"] for {n:int(1,100)} or nil for {n:int}.
+type ConstraintFactory func(args []string) (Constraint, error)
+
+var (
+	constraintsMu sync.RWMutex
+	constraints   = map[string]ConstraintFactory{
+		"int":   intConstraint,
+		"float": floatConstraint,
+		"bool":  boolConstraint,
+		"alpha": patternConstraint(regexp.MustCompile(`^[A-Za-z]+$`)),
+		"uuid":  patternConstraint(regexp.MustCompile(`^[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12}$`)),
+		"slug":  patternConstraint(regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)),
+		"date":  dateConstraint,
+	}
+
+	// constraintExpr matches a constraint segment pattern: a name with optional arguments.
+	constraintExpr = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)(?:\((.*)\))?$`)
+)
+
+// RegisterConstraint registers a constraint type for use in route patterns as
+// {name:type} or {name:type(args)}. Registering a built-in name replaces it.
+// Constraints must be registered before the routes using them.
+func RegisterConstraint(name string, factory ConstraintFactory) {
+	constraintsMu.Lock()
+	defer constraintsMu.Unlock()
+
+	constraints[name] = factory
+}
+
+// parseConstraint builds the constraint for the pattern of a {name:pattern}
+// segment. It reports false if the pattern does not name a registered
+// constraint, in which case the pattern is a regular expression.
+func parseConstraint(pattern string) (Constraint, bool, error) {
+	m := constraintExpr.FindStringSubmatch(pattern)
+	if m == nil {
+		return nil, false, nil
+	}
+	constraintsMu.RLock()
+	factory, ok := constraints[m[1]]
+	constraintsMu.RUnlock()
+	if !ok {
+		return nil, false, nil
+	}
+
+	var args []string
+	if strings.TrimSpace(m[2]) != "" {
+		args = strings.Split(m[2], ",")
+		for i := range args {
+			args[i] = strings.TrimSpace(args[i])
+		}
+	}
+	constraint, err := factory(args)
+	if err != nil {
+		return nil, true, fmt.Errorf("constraint %s: %v", m[1], err)
+	}
+	return constraint, true, nil
+}
+
+// intConstraint matches integers, optionally within the range given by int(min,max).
+func intConstraint(args []string) (Constraint, error) {
+	if len(args) != 0 && len(args) != 2 {
+		return nil, fmt.Errorf("expected no arguments or min and max, got %d arguments", len(args))
+	}
+	lo, hi := int64(-1<<63), int64(1<<63-1)
+	if len(args) == 2 {
+		var err error
+		if lo, err = strconv.ParseInt(args[0], 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid min %q", args[0])
+		}
+		if hi, err = strconv.ParseInt(args[1], 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid max %q", args[1])
+		}
+	}
+	return func(value string) (interface{}, bool) {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < lo || n > hi {
+			return nil, false
+		}
+		return n, true
+	}, nil
+}
+
+// floatConstraint matches floating-point numbers.
+func floatConstraint(args []string) (Constraint, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("expected no arguments, got %d", len(args))
+	}
+	return func(value string) (interface{}, bool) {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, false
+		}
+		return f, true
+	}, nil
+}
+
+// boolConstraint matches the values accepted by strconv.ParseBool.
+func boolConstraint(args []string) (Constraint, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("expected no arguments, got %d", len(args))
+	}
+	return func(value string) (interface{}, bool) {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, false
+		}
+		return b, true
+	}, nil
+}
+
+// dateConstraint matches dates in the layout given by date(layout), 2006-01-02 by default.
+func dateConstraint(args []string) (Constraint, error) {
+	layout := "2006-01-02"
+	switch len(args) {
+	case 0:
+	case 1:
+		layout = args[0]
+	default:
+		return nil, fmt.Errorf("expected at most a layout, got %d arguments", len(args))
+	}
+	return func(value string) (interface{}, bool) {
+		t, err := time.Parse(layout, value)
+		if err != nil {
+			return nil, false
+		}
+		return t, true
+	}, nil
+}
+
+// patternConstraint returns a factory for constraints that match re and keep the value as a string.
+func patternConstraint(re *regexp.Regexp) ConstraintFactory {
+	return func(args []string) (Constraint, error) {
+		if len(args) != 0 {
+			return nil, fmt.Errorf("expected no arguments, got %d", len(args))
+		}
+		return func(value string) (interface{}, bool) {
+			if !re.MatchString(value) {
+				return nil, false
+			}
+			return value, true
+		}, nil
+	}
+}
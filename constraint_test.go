@@ -0,0 +1,117 @@
+package invoke
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestBuiltinConstraints(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{"/n/{v:int}", "/n/-42", true},
+		{"/n/{v:int}", "/n/4.2", false},
+		{"/n/{v:int(1,100)}", "/n/1", true},
+		{"/n/{v:int(1,100)}", "/n/100", true},
+		{"/n/{v:int(1,100)}", "/n/0", false},
+		{"/n/{v:int(1,100)}", "/n/101", false},
+		{"/n/{v:float}", "/n/4.2", true},
+		{"/n/{v:float}", "/n/four", false},
+		{"/n/{v:bool}", "/n/true", true},
+		{"/n/{v:bool}", "/n/yes", false},
+		{"/n/{v:alpha}", "/n/abc", true},
+		{"/n/{v:alpha}", "/n/abc1", false},
+		{"/n/{v:uuid}", "/n/123e4567-e89b-12d3-a456-426614174000", true},
+		{"/n/{v:uuid}", "/n/123e4567", false},
+		{"/n/{v:slug}", "/n/hello-world", true},
+		{"/n/{v:slug}", "/n/hello--world", false},
+		{"/n/{v:date}", "/n/2024-02-29", true},
+		{"/n/{v:date}", "/n/2023-02-29", false},
+		{"/n/{v:date(200601)}", "/n/202402", true},
+	}
+	for _, tt := range tests {
+		r := NewRouter()
+		r.SetCaseSensitive(true)
+		r.GET(tt.pattern, func(ctx *HttpContext) {})
+		if code, _ := serveBody(r, "GET", tt.path); (code == 200) != tt.match {
+			t.Errorf("%s on %s = %d, want match %v", tt.pattern, tt.path, code, tt.match)
+		}
+	}
+}
+
+func TestInvalidConstraintArgsPanic(t *testing.T) {
+	for _, pattern := range []string{"/n/{v:int(1)}", "/n/{v:int(a,b)}", "/n/{v:bool(1)}", "/n/{v:date(a,b)}"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("registering %s did not panic", pattern)
+				}
+			}()
+			NewRouter().GET(pattern, func(ctx *HttpContext) {})
+		}()
+	}
+}
+
+func TestRegisterConstraint(t *testing.T) {
+	RegisterConstraint("multipleof", func(args []string) (Constraint, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("expected a divisor, got %d arguments", len(args))
+		}
+		d, err := strconv.Atoi(args[0])
+		if err != nil || d == 0 {
+			return nil, fmt.Errorf("invalid divisor %q", args[0])
+		}
+		return func(value string) (interface{}, bool) {
+			n, err := strconv.Atoi(value)
+			if err != nil || n%d != 0 {
+				return nil, false
+			}
+			return n, true
+		}, nil
+	})
+
+	r := NewRouter()
+	r.GET("/batch/{size:multipleof(4)}", func(ctx *HttpContext) {
+		n, err := ctx.ParamInt("size")
+		ctx.WriteString(fmt.Sprint(n, err))
+	})
+	if code, body := serveBody(r, "GET", "/batch/12"); code != 200 || body != "12 <nil>" {
+		t.Errorf("GET /batch/12 = %d %q", code, body)
+	}
+	if code, _ := serveBody(r, "GET", "/batch/10"); code != 404 {
+		t.Errorf("GET /batch/10 = %d, want 404", code)
+	}
+}
+
+func TestTypedParams(t *testing.T) {
+	r := NewRouter()
+	r.GET("/t/{i:int}/{f:float}/{b:bool}/{d:date}/:s", func(ctx *HttpContext) {
+		i, err1 := ctx.ParamInt("i")
+		f, err2 := ctx.ParamFloat("f")
+		b, err3 := ctx.ParamBool("b")
+		d, err4 := ctx.ParamTime("d")
+		typed, _ := ctx.ParamValue("i")
+		raw, _ := ctx.ParamValue("s")
+		ctx.WriteString(fmt.Sprintf("%T %d %v %v %s %v %v", typed, i, f, b, d.Format(time.RFC3339), raw, []error{err1, err2, err3, err4}))
+	})
+	_, body := serveBody(r, "GET", "/t/7/1.5/true/2024-01-02/x")
+	if want := "int64 7 1.5 true 2024-01-02T00:00:00Z x [<nil> <nil> <nil> <nil>]"; body != want {
+		t.Errorf("typed getters = %q, want %q", body, want)
+	}
+
+	r.GET("/s/:v", func(ctx *HttpContext) {
+		n, err := ctx.ParamInt("v")
+		_, missing := ctx.ParamFloat("nope")
+		ctx.WriteString(fmt.Sprint(n, err == nil, missing != nil))
+	})
+	if _, body := serveBody(r, "GET", "/s/12"); body != "12 true true" {
+		t.Errorf("ParamInt on an untyped param = %q", body)
+	}
+	if _, body := serveBody(r, "GET", "/s/abc"); body != "0 false true" {
+		t.Errorf("ParamInt on a non-integer = %q", body)
+	}
+}
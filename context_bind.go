@@ -0,0 +1,346 @@
+package invoke
+
+import (
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldError describes a struct field that could not be bound or is invalid.
+type FieldError struct {
+	Field   string `json:"field"`            // Go field path, e.g., Address.City.
+	Source  string `json:"source,omitempty"` // Where the value came from: path, query, form, header or body.
+	Key     string `json:"key,omitempty"`    // Name of the value in its source.
+	Value   string `json:"value,omitempty"`  // Offending value, if any.
+	Rule    string `json:"rule,omitempty"`   // Failed validate rule, e.g., min.
+	Message string `json:"message"`          // What is wrong with the value.
+}
+
+// BindError lists every field that could not be bound.
+type BindError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+// Error returns the failing fields, separated by semicolons.
+func (e *BindError) Error() string {
+	return "bind failed: " + fieldErrorsString(e.Fields)
+}
+
+// fieldErrorsString joins the fields and their messages with semicolons.
+func fieldErrorsString(fields []FieldError) string {
+	messages := make([]string, len(fields))
+	for i, f := range fields {
+		messages[i] = f.Field + ": " + f.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// bindSources are the struct tags read by Bind, in the order they are applied.
+var bindSources = []string{"path", "query", "form", "header"}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	durationType   = reflect.TypeOf(time.Duration(0))
+	fileHeaderType = reflect.TypeOf((*multipart.FileHeader)(nil))
+	unmarshalType  = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Bind fills the struct v points to from the request. The body is decoded
+// first according to its Content-Type: JSON and XML bodies through the json
+// and xml tags, while urlencoded and multipart forms are read through form
+// tags. Fields tagged path, query, form or header are then set from the route
+// parameters, query string, form and headers, e.g.:
+//
+//	type Request struct {
+//	    ID      int64     `path:"id"`
+//	    Tags    []string  `query:"tag"`
+//	    Since   time.Time `query:"since" layout:"2006-01-02"`
+//	    Token   *string   `header:"X-Token"`
+//	    Name    string    `json:"name" form:"name"`
+//	    Avatar  *multipart.FileHeader `form:"avatar"`
+//	    Paging                         // Embedded and nested structs are filled too.
+//	}
+//
+// Slices, pointers, time.Time (RFC 3339 unless a layout tag is given),
+// time.Duration and encoding.TextUnmarshaler fields are supported. Every field
+// that cannot be bound is reported in a *BindError.
+func (ctx *HttpContext) Bind(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bind target must be a non-nil pointer to a struct, got %T", v)
+	}
+
+	var fields []FieldError
+	if err := ctx.bindBody(v); err != nil {
+		var bindErr *BindError
+		if !errors.As(err, &bindErr) {
+			return err
+		}
+		fields = append(fields, bindErr.Fields...)
+	}
+	fields = ctx.bindStruct(rv.Elem(), "", fields)
+	if len(fields) > 0 {
+		return &BindError{Fields: fields}
+	}
+	return nil
+}
+
+// bindBody decodes a JSON or XML body into v and parses form bodies. Body
+// errors are returned as a *BindError with the body source.
+func (ctx *HttpContext) bindBody(v interface{}) error {
+	req := ctx.Req
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	var err error
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		err = json.NewDecoder(req.Body).Decode(v)
+	case mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		err = xml.NewDecoder(req.Body).Decode(v)
+	case mediaType == "application/x-www-form-urlencoded":
+		err = req.ParseForm()
+	case mediaType == "multipart/form-data":
+		err = req.ParseMultipartForm(MaxMultipartBytes)
+	default:
+		return nil
+	}
+	if err == nil || err == io.EOF {
+		return nil
+	}
+
+	field := FieldError{Source: "body", Message: err.Error()}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		field.Field = typeErr.Field
+		field.Key = typeErr.Field
+		field.Value = typeErr.Value
+		field.Message = "cannot be decoded into " + typeErr.Type.String()
+	}
+	return &BindError{Fields: []FieldError{field}}
+}
+
+// bindStruct sets the tagged fields of the struct value and returns fields
+// with the errors appended. Prefix is the field path of the struct.
+func (ctx *HttpContext) bindStruct(rv reflect.Value, prefix string, fields []FieldError) []FieldError {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue // Unexported field.
+		}
+		fv := rv.Field(i)
+		name := prefix // Embedded fields are promoted.
+		if !sf.Anonymous && prefix != "" {
+			name = prefix + "." + sf.Name
+		} else if !sf.Anonymous {
+			name = sf.Name
+		}
+
+		source, key := bindTag(sf)
+		if source == "-" {
+			continue
+		}
+		if source == "" {
+			// Fill untagged nested structs, allocating pointers only if a value is set.
+			if ft := sf.Type; ft.Kind() == reflect.Struct && ft != timeType {
+				fields = ctx.bindStruct(fv, name, fields)
+			} else if ft.Kind() == reflect.Ptr && ft.Elem().Kind() == reflect.Struct && ft.Elem() != timeType && fv.CanSet() {
+				target := fv
+				if fv.IsNil() {
+					target = reflect.New(ft.Elem())
+				}
+				before := len(fields)
+				fields = ctx.bindStruct(target.Elem(), name, fields)
+				if len(fields) > before || !target.Elem().IsZero() {
+					fv.Set(target)
+				}
+			}
+			continue
+		}
+		if !fv.CanSet() {
+			continue
+		}
+
+		if source == "form" && bindFiles(fv, ctx.Req, key) {
+			continue
+		}
+		values, ok := ctx.bindValues(source, key)
+		if !ok {
+			continue
+		}
+		if err := setField(fv, values, sf.Tag.Get("layout")); err != nil {
+			fields = append(fields, FieldError{Field: name, Source: source, Key: key, Value: strings.Join(values, ","), Message: err.Error()})
+		}
+	}
+	return fields
+}
+
+// bindTag returns the source and key of a field tagged for Bind, "" if it has
+// none, or "-" if it is excluded.
+func bindTag(sf reflect.StructField) (string, string) {
+	for _, source := range bindSources {
+		tag, ok := sf.Tag.Lookup(source)
+		if !ok {
+			continue
+		}
+		key := strings.Split(tag, ",")[0]
+		if key == "-" {
+			return "-", ""
+		}
+		if key == "" {
+			key = sf.Name
+		}
+		return source, key
+	}
+	return "", ""
+}
+
+// bindValues returns the values of key in the source and whether there are any.
+func (ctx *HttpContext) bindValues(source, key string) ([]string, bool) {
+	req := ctx.Req
+	switch source {
+	case "path":
+		value, ok := ctx.Params.Lookup(key)
+		return []string{value}, ok
+	case "query":
+		values, ok := req.URL.Query()[key]
+		return values, ok
+	case "header":
+		values := req.Header.Values(key)
+		return values, len(values) > 0
+	default:
+		values, ok := req.PostForm[key]
+		return values, ok
+	}
+}
+
+// bindFiles sets *multipart.FileHeader and []*multipart.FileHeader fields
+// from the uploaded files and reports whether the field is of such a type.
+func bindFiles(fv reflect.Value, req *http.Request, key string) bool {
+	ft := fv.Type()
+	if ft != fileHeaderType && (ft.Kind() != reflect.Slice || ft.Elem() != fileHeaderType) {
+		return false
+	}
+	if req.MultipartForm == nil || len(req.MultipartForm.File[key]) == 0 {
+		return true
+	}
+	files := req.MultipartForm.File[key]
+	if ft == fileHeaderType {
+		fv.Set(reflect.ValueOf(files[0]))
+	} else {
+		fv.Set(reflect.ValueOf(files))
+	}
+	return true
+}
+
+// setField converts the values and stores them in the field.
+func setField(fv reflect.Value, values []string, layout string) error {
+	ft := fv.Type()
+	switch {
+	case ft.Kind() == reflect.Ptr:
+		elem := reflect.New(ft.Elem())
+		if err := setField(elem.Elem(), values, layout); err != nil {
+			return err
+		}
+		fv.Set(elem)
+		return nil
+	case ft.Kind() == reflect.Slice && ft.Elem().Kind() != reflect.Uint8:
+		slice := reflect.MakeSlice(ft, len(values), len(values))
+		for i, value := range values {
+			if err := setField(slice.Index(i), []string{value}, layout); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	}
+
+	value := ""
+	if len(values) > 0 {
+		value = values[0]
+	}
+	if reflect.PtrTo(ft).Implements(unmarshalType) && ft != timeType {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+	switch {
+	case ft == timeType:
+		t, err := parseTime(value, layout)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(t))
+		return nil
+	case ft == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("cannot parse %q as a duration", value)
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	switch ft.Kind() {
+	case reflect.String:
+		fv.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("cannot parse %q as a boolean", value)
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, ft.Bits())
+		if err != nil {
+			return fmt.Errorf("cannot parse %q as %s", value, ft.Kind())
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, ft.Bits())
+		if err != nil {
+			return fmt.Errorf("cannot parse %q as %s", value, ft.Kind())
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, ft.Bits())
+		if err != nil {
+			return fmt.Errorf("cannot parse %q as a number", value)
+		}
+		fv.SetFloat(f)
+	case reflect.Slice: // []byte
+		fv.SetBytes([]byte(value))
+	default:
+		return fmt.Errorf("unsupported field type %s", ft)
+	}
+	return nil
+}
+
+// parseTime parses a time with the layout, or as RFC 3339 or a 2006-01-02 date if there is none.
+func parseTime(value, layout string) (time.Time, error) {
+	if layout != "" {
+		t, err := time.Parse(layout, value)
+		if err != nil {
+			return t, fmt.Errorf("cannot parse %q as a time in layout %s", value, layout)
+		}
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return t, fmt.Errorf("cannot parse %q as an RFC 3339 time or a date", value)
+	}
+	return t, nil
+}
@@ -0,0 +1,177 @@
+package invoke
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// bindRequest serves the request through a route with the pattern and binds it into v.
+func bindRequest(pattern string, req *http.Request, v interface{}) error {
+	var err error
+	r := NewRouter()
+	r.Handle([]string{req.Method}, pattern, func(ctx *HttpContext) { err = ctx.Bind(v) })
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	return err
+}
+
+// newBindRequest returns a request with the body and Content-Type.
+func newBindRequest(method, target, contentType string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, target, body)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req
+}
+
+type bindPaging struct {
+	Page int `query:"page"`
+	Size int `query:"size"`
+}
+
+type bindAddress struct {
+	City string `query:"city"`
+}
+
+type bindUser struct {
+	ID      int64         `path:"id"`
+	Tags    []string      `query:"tag"`
+	IDs     []int         `query:"ids"`
+	Since   time.Time     `query:"since" layout:"2006-01-02"`
+	Wait    time.Duration `query:"wait"`
+	Token   *string       `header:"X-Token"`
+	Missing *string       `header:"X-Missing"`
+	Name    string        `json:"name" xml:"name" form:"name"`
+	Age     int           `json:"age" xml:"age"`
+	Address *bindAddress
+	Skipped *bindAddress `query:"-"`
+	bindPaging
+}
+
+func TestBindSources(t *testing.T) {
+	req := newBindRequest("POST", "/users/7?tag=a&tag=b&ids=1&ids=2&since=2024-05-01&wait=2s&page=3&city=Oslo",
+		"application/json", strings.NewReader(`{"name":"ann","age":30}`))
+	req.Header.Set("X-Token", "secret")
+	var u bindUser
+	if err := bindRequest("/users/:id", req, &u); err != nil {
+		t.Fatal(err)
+	}
+	switch {
+	case u.ID != 7:
+		t.Errorf("path ID = %d", u.ID)
+	case strings.Join(u.Tags, ",") != "a,b" || len(u.IDs) != 2 || u.IDs[1] != 2:
+		t.Errorf("query slices = %v %v", u.Tags, u.IDs)
+	case !u.Since.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) || u.Wait != 2*time.Second:
+		t.Errorf("query time and duration = %v %v", u.Since, u.Wait)
+	case u.Token == nil || *u.Token != "secret" || u.Missing != nil:
+		t.Errorf("header pointers = %v %v", u.Token, u.Missing)
+	case u.Name != "ann" || u.Age != 30:
+		t.Errorf("JSON body = %q %d", u.Name, u.Age)
+	case u.Page != 3 || u.Size != 0:
+		t.Errorf("embedded struct = %+v", u.bindPaging)
+	case u.Address == nil || u.Address.City != "Oslo" || u.Skipped != nil:
+		t.Errorf("nested structs = %+v %+v", u.Address, u.Skipped)
+	}
+
+	u = bindUser{}
+	req = newBindRequest("PUT", "/users/8", "application/xml", strings.NewReader("<user><name>bob</name><age>40</age></user>"))
+	if err := bindRequest("/users/:id", req, &u); err != nil || u.ID != 8 || u.Name != "bob" || u.Age != 40 || u.Address != nil {
+		t.Errorf("XML body = %+v, %v", u, err)
+	}
+
+	u = bindUser{}
+	req = newBindRequest("POST", "/users/9", "application/x-www-form-urlencoded", strings.NewReader("name=cy"))
+	if err := bindRequest("/users/:id", req, &u); err != nil || u.Name != "cy" {
+		t.Errorf("urlencoded form = %+v, %v", u, err)
+	}
+}
+
+func TestBindMultipart(t *testing.T) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("name", "dee")
+	fw, _ := mw.CreateFormFile("avatar", "me.png")
+	fw.Write([]byte("png"))
+	for _, name := range []string{"a.txt", "b.txt"} {
+		fw, _ = mw.CreateFormFile("docs", name)
+		fw.Write([]byte(name))
+	}
+	mw.Close()
+
+	var in struct {
+		Name   string                  `form:"name"`
+		Avatar *multipart.FileHeader   `form:"avatar"`
+		Docs   []*multipart.FileHeader `form:"docs"`
+		None   *multipart.FileHeader   `form:"none"`
+	}
+	req := newBindRequest("POST", "/upload", mw.FormDataContentType(), &buf)
+	if err := bindRequest("/upload", req, &in); err != nil {
+		t.Fatal(err)
+	}
+	if in.Name != "dee" || in.Avatar == nil || in.Avatar.Filename != "me.png" || len(in.Docs) != 2 || in.Docs[1].Filename != "b.txt" || in.None != nil {
+		t.Errorf("multipart form = %+v", in)
+	}
+}
+
+func TestBindReportsAllFields(t *testing.T) {
+	req := newBindRequest("POST", "/users/x?since=2024/05/01&page=two&ids=1&ids=b&wait=soon", "application/json", strings.NewReader(`{"age":"old"}`))
+	var u bindUser
+	err := bindRequest("/users/:id", req, &u)
+	var bindErr *BindError
+	if !errors.As(err, &bindErr) {
+		t.Fatalf("Bind error = %v, want a *BindError", err)
+	}
+	var got []string
+	for _, f := range bindErr.Fields {
+		got = append(got, f.Source+":"+f.Field+"="+f.Value)
+	}
+	want := "body:age=string path:ID=x query:IDs=1,b query:Since=2024/05/01 query:Wait=soon query:Page=two"
+	if strings.Join(got, " ") != want {
+		t.Errorf("failing fields = %s, want %s", strings.Join(got, " "), want)
+	}
+}
+
+func TestBindBodyErrors(t *testing.T) {
+	for _, tt := range []struct{ name, contentType, body string }{
+		{"json", "application/json", `{"name":`},
+		{"xml", "application/xml", "<user><name>"},
+		{"urlencoded", "application/x-www-form-urlencoded", "name=%zz"},
+		{"multipart", "multipart/form-data; boundary=x", "not multipart"},
+	} {
+		var u bindUser
+		err := bindRequest("/users", newBindRequest("POST", "/users", tt.contentType, strings.NewReader(tt.body)), &u)
+		var bindErr *BindError
+		if !errors.As(err, &bindErr) || len(bindErr.Fields) != 1 || bindErr.Fields[0].Source != "body" {
+			t.Errorf("%s: Bind error = %v, want a *BindError for the body", tt.name, err)
+		}
+	}
+
+	r := NewRouter()
+	r.SetErrorStatus(true)
+	r.POST("/users", func(ctx *HttpContext) error {
+		var u bindUser
+		return ctx.Bind(&u)
+	})
+	req := newBindRequest("POST", "/users", "application/x-www-form-urlencoded", strings.NewReader("name=%zz"))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("malformed form = %d, want 400", w.Code)
+	}
+}
+
+func TestBindTarget(t *testing.T) {
+	ctx := &HttpContext{Req: httptest.NewRequest("GET", "/", nil)}
+	var u bindUser
+	for _, v := range []interface{}{u, (*bindUser)(nil), new(int)} {
+		if ctx.Bind(v) == nil {
+			t.Errorf("Bind(%T) did not fail", v)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package invoke
+
+import "net/http"
+
+// Next runs the rest of the middleware chain. Middleware calls it to run code
+// after the handler; a middleware that does not call it is followed by the
+// next one once it returns, unless it aborted the chain.
+func (ctx *HttpContext) Next() {
+	for ctx.index < len(ctx.handlers) && !ctx.aborted {
+		handler := ctx.handlers[ctx.index]
+		ctx.index++
+		handler(ctx)
+	}
+}
+
+// Abort stops the chain: handlers after the current one are not run.
+// Middleware already running still finishes.
+func (ctx *HttpContext) Abort() {
+	ctx.aborted = true
+}
+
+// AbortWithStatus writes the status code and stops the chain.
+func (ctx *HttpContext) AbortWithStatus(statusCode int) {
+	ctx.W.WriteHeader(statusCode)
+	ctx.Abort()
+}
+
+// IsAborted reports whether the chain was stopped.
+func (ctx *HttpContext) IsAborted() bool {
+	return ctx.aborted
+}
+
+// reset prepares a pooled context for a new request.
+func (ctx *HttpContext) reset(w http.ResponseWriter, req *http.Request) {
+	ctx.W = w
+	ctx.Req = req
+	ctx.Params = ctx.Params[:0]
+	ctx.Route = nil
+	ctx.handlers = nil
+	ctx.index = 0
+	ctx.aborted = false
+	ctx.table = nil
+	for key := range ctx.values {
+		delete(ctx.values, key) // Keep the map for the next request.
+	}
+}
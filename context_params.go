@@ -17,7 +17,7 @@ type FileHandler func(key string, file multipart.File, fileHeader *multipart.Fil
 
 // parmQuery is a helper function to retrieve a parameter value from various sources, including URL parameters, form data, and multipart form data.
 func (ctx *HttpContext) parmQuery(key string) string {
-	if v, ok := ctx.Params[key]; ok {
+	if v, ok := ctx.Params.Lookup(key); ok {
 		return v
 	}
 	// Parse form data if not already parsed
@@ -57,6 +57,15 @@ func (ctx *HttpContext) UserAgent() string {
 	return ctx.Req.UserAgent()
 }
 
+// Meta returns the metadata value of the matched route for the given key.
+func (ctx *HttpContext) Meta(key string) (interface{}, bool) {
+	if ctx.Route == nil {
+		return nil, false
+	}
+	value, ok := ctx.Route.Meta[key]
+	return value, ok
+}
+
 // getRealIP retrieves the real remote IP address from the HTTP request.
 // It considers multiple headers and fields to handle scenarios involving proxies and load balancers.
 func getRealIP(ctx *HttpContext) string {
@@ -17,7 +17,14 @@ import (
 type HttpContext struct {
 	W      http.ResponseWriter
 	Req    *http.Request
-	Params map[string]string
+	Params Params // Path parameters of the matched route.
+	Route  *Route // Matched route, nil if no route matched.
+
+	handlers []func(ctx *HttpContext)    // Middleware chain ending with the route handler.
+	index    int                         // Position of the next handler to run in the chain.
+	aborted  bool                        // Whether the chain was aborted.
+	values   map[interface{}]interface{} // Request-scoped values stored with Set.
+	table    *routeTable                 // Route table serving the request, nil outside a router.
 }
 
 // ResponseResult represents a unified response structure.
@@ -67,6 +74,12 @@ func (ec ErrorCode) String() string {
 	return "Unknown"
 }
 
+// Error returns the name of the error code, so that codes can be returned and
+// wrapped as errors, e.g., fmt.Errorf("user %d: %w", id, AuthError).
+func (ec ErrorCode) Error() string {
+	return ec.String()
+}
+
 // getCallerInfo retrieves the filename and line number of the caller.
 func getCallerInfo() string {
 	_, file, line, ok := runtime.Caller(2) // Adjust the skip value as needed
@@ -77,50 +90,48 @@ func getCallerInfo() string {
 }
 
 // WriteErrorJSON writes an error message as JSON to the response with the specified status code.
+// The status code is an HTTP status or an ErrorCode. HTTP 200 is sent unless
+// SetErrorStatus is on or the envelope is ProblemEnvelope, in which case the
+// HTTP status, the ErrorCodeStatus of the ErrorCode, or 500 for other codes is sent.
 func (ctx *HttpContext) WriteErrorJSON(statusCode interface{}, errMsg interface{}) {
-	ctx.W.Header().Set("Content-Type", "application/json")
-	ctx.W.WriteHeader(http.StatusOK)
-
 	var code int
+	var status = http.StatusInternalServerError
 	var errMsgFormatted = errMsg
 	// Determine the type of statusCode and handle accordingly
 	switch v := statusCode.(type) {
 	case int:
 		code = int(v)
 	case int64:
-		code = int(64)
+		code = int(v)
 	case ErrorCode:
 		code = int(v)
+		if s, ok := ErrorCodeStatus[v]; ok {
+			status = s
+		}
 		if str, ok := errMsg.(string); ok {
 			errMsgFormatted = fmt.Sprintf("%v: %v", v.String(), str)
 		}
 	default:
 		code = int(OtherError)
 	}
-
-	response := ResponseResult{
-		Code: code,
-		URL:  ctx.Req.URL.Path,
-		Desc: getCallerInfo(),
-		Data: errMsgFormatted,
+	if _, ok := statusCode.(ErrorCode); !ok && code >= 100 && code <= 599 {
+		status = code
 	}
 
-	json.NewEncoder(ctx.W).Encode(response)
+	response := &Response{Status: status, Code: code, Error: true, Data: errMsgFormatted}
+	if !ctx.production() {
+		response.Desc = getCallerInfo()
+	}
+	ctx.writeResponse(response)
 }
 
 // WriteSuccessJSON writes an object as JSON to the response with a 200 status code.
 func (ctx *HttpContext) WriteSuccessJSON(data interface{}) {
-	ctx.W.Header().Set("Content-Type", "application/json")
-	ctx.W.WriteHeader(http.StatusOK)
-
-	response := ResponseResult{
-		Code: http.StatusOK,
-		URL:  ctx.Req.URL.Path,
-		Desc: getCallerInfo(),
-		Data: data,
+	response := &Response{Status: http.StatusOK, Code: http.StatusOK, Data: data}
+	if !ctx.production() {
+		response.Desc = getCallerInfo()
 	}
-
-	json.NewEncoder(ctx.W).Encode(response)
+	ctx.writeResponse(response)
 }
 
 // WriteErrorXML writes an error message as XML to the response with the specified status code.
@@ -130,9 +141,11 @@ func (ctx *HttpContext) WriteErrorXML(statusCode int, errMsg interface{}) {
 	response := ResponseResult{
 		Code: statusCode,
 		URL:  ctx.Req.URL.Path,
-		Desc: getCallerInfo(),
 		Data: errMsg,
 	}
+	if !ctx.production() {
+		response.Desc = getCallerInfo()
+	}
 	xml.NewEncoder(ctx.W).Encode(response)
 }
 
@@ -143,9 +156,11 @@ func (ctx *HttpContext) WriteSuccessXML(data interface{}) {
 	response := ResponseResult{
 		Code: http.StatusOK,
 		URL:  ctx.Req.URL.Path,
-		Desc: getCallerInfo(),
 		Data: data,
 	}
+	if !ctx.production() {
+		response.Desc = getCallerInfo()
+	}
 	xml.NewEncoder(ctx.W).Encode(response)
 }
 
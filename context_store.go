@@ -0,0 +1,80 @@
+package invoke
+
+import "fmt"
+
+// Set stores a value for the rest of the request under key, e.g., the
+// authenticated user in a before hook. It is visible to all hooks, middleware
+// and the handler of the same request.
+func (ctx *HttpContext) Set(key string, value interface{}) {
+	ctx.set(key, value)
+}
+
+// Get returns the value stored under key with Set.
+func (ctx *HttpContext) Get(key string) (interface{}, bool) {
+	value, ok := ctx.values[key]
+	return value, ok
+}
+
+// MustGet returns the value stored under key with Set, panicking if there is none.
+func (ctx *HttpContext) MustGet(key string) interface{} {
+	value, ok := ctx.Get(key)
+	if !ok {
+		panic(fmt.Sprintf("Error: Key '%s' is not set on the request.\n", key))
+	}
+	return value
+}
+
+// set stores a value under a string or typed key.
+func (ctx *HttpContext) set(key, value interface{}) {
+	if ctx.values == nil {
+		ctx.values = make(map[interface{}]interface{})
+	}
+	ctx.values[key] = value
+}
+
+// GetAs returns the value stored under key with Set if it has type T.
+func GetAs[T any](ctx *HttpContext, key string) (T, bool) {
+	value, ok := ctx.values[key].(T)
+	return value, ok
+}
+
+// Key is a typed key for request-scoped values. Keys are compared by
+// identity, so two keys with the same name never clash:
+//
+//	var UserKey = invoke.NewKey[*User]("user")
+//
+//	UserKey.Set(ctx, user)
+//	user, ok := UserKey.Get(ctx)
+type Key[T any] struct {
+	name string
+}
+
+// NewKey returns a new typed key. The name is used in error messages only.
+func NewKey[T any](name string) *Key[T] {
+	return &Key[T]{name: name}
+}
+
+// Name returns the name of the key.
+func (k *Key[T]) Name() string {
+	return k.name
+}
+
+// Set stores the value for the rest of the request.
+func (k *Key[T]) Set(ctx *HttpContext, value T) {
+	ctx.set(k, value)
+}
+
+// Get returns the value stored under the key.
+func (k *Key[T]) Get(ctx *HttpContext) (T, bool) {
+	value, ok := ctx.values[k].(T)
+	return value, ok
+}
+
+// MustGet returns the value stored under the key, panicking if there is none.
+func (k *Key[T]) MustGet(ctx *HttpContext) T {
+	value, ok := k.Get(ctx)
+	if !ok {
+		panic(fmt.Sprintf("Error: Key '%s' is not set on the request.\n", k.name))
+	}
+	return value
+}
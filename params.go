@@ -0,0 +1,133 @@
+package invoke
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// PathParam is a path parameter captured by a route.
+type PathParam struct {
+	Key   string      `json:"key"`             // Parameter name, e.g., id for /user/:id.
+	Value string      `json:"value"`           // Captured text of the request path.
+	Typed interface{} `json:"typed,omitempty"` // Converted value of typed segments such as {id:int}, nil otherwise.
+}
+
+// Params holds the path parameters of a request in the order of the route pattern.
+// The slice is reused across requests and must not be kept after the handler returns.
+type Params []PathParam
+
+// Get returns the value of the parameter with the given name, or "" if there is none.
+func (ps Params) Get(key string) string {
+	value, _ := ps.Lookup(key)
+	return value
+}
+
+// Lookup returns the value of the parameter with the given name and whether it exists.
+func (ps Params) Lookup(key string) (string, bool) {
+	for _, p := range ps {
+		if p.Key == key {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// Map returns the parameters as a new map.
+func (ps Params) Map() map[string]string {
+	m := make(map[string]string, len(ps))
+	for _, p := range ps {
+		m[p.Key] = p.Value
+	}
+	return m
+}
+
+// Param returns the value of the path parameter with the given name, or "" if there is none.
+func (ctx *HttpContext) Param(key string) string {
+	return ctx.Params.Get(key)
+}
+
+// Request returns the request with the path parameters stored in its context,
+// for net/http code that reads them with GetParams. ServeHTTP does not store
+// them on ctx.Req, so that matching does not allocate; the request is built
+// on each call.
+func (ctx *HttpContext) Request() *http.Request {
+	return ctx.Req.WithContext(contextWithParams(ctx.Req.Context(), ctx.Params.Map()))
+}
+
+// ParamValue returns the converted value of a typed path parameter such as
+// {id:int}, or the captured text of other parameters.
+func (ctx *HttpContext) ParamValue(key string) (interface{}, bool) {
+	for _, p := range ctx.Params {
+		if p.Key == key {
+			if p.Typed != nil {
+				return p.Typed, true
+			}
+			return p.Value, true
+		}
+	}
+	return nil, false
+}
+
+// ParamInt returns the path parameter as an integer. Values of {id:int}
+// segments are already converted; other parameters are parsed.
+func (ctx *HttpContext) ParamInt(key string) (int64, error) {
+	value, ok := ctx.ParamValue(key)
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	}
+	return 0, paramTypeError(key, value, ok, "an integer")
+}
+
+// ParamFloat returns the path parameter as a floating-point number.
+func (ctx *HttpContext) ParamFloat(key string) (float64, error) {
+	value, ok := ctx.ParamValue(key)
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	}
+	return 0, paramTypeError(key, value, ok, "a number")
+}
+
+// ParamBool returns the path parameter as a boolean.
+func (ctx *HttpContext) ParamBool(key string) (bool, error) {
+	value, ok := ctx.ParamValue(key)
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		return strconv.ParseBool(v)
+	}
+	return false, paramTypeError(key, value, ok, "a boolean")
+}
+
+// ParamTime returns the path parameter as a time. Values of {d:date} segments
+// are already converted; other parameters are parsed as 2006-01-02 dates.
+func (ctx *HttpContext) ParamTime(key string) (time.Time, error) {
+	value, ok := ctx.ParamValue(key)
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		return time.Parse("2006-01-02", v)
+	}
+	return time.Time{}, paramTypeError(key, value, ok, "a time")
+}
+
+// paramTypeError returns the error for a missing path parameter or one whose typed value has the wrong type.
+func paramTypeError(key string, value interface{}, ok bool, want string) error {
+	if !ok {
+		return fmt.Errorf("path parameter %q not found", key)
+	}
+	return fmt.Errorf("path parameter %q is %T, not %s", key, value, want)
+}
@@ -0,0 +1,150 @@
+package invoke
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Mode controls whether responses include debug info.
+type Mode int
+
+const (
+	// ModeDevelopment adds debug info to responses: the caller's file:line in
+	// Desc, and the message of unmapped errors. It is the default.
+	ModeDevelopment Mode = iota
+	// ModeProduction leaves debug info out of responses.
+	ModeProduction
+)
+
+// Response is a response of WriteSuccessJSON, WriteErrorJSON or the error
+// handler before it is wrapped by the router's Envelope.
+type Response struct {
+	Status int         // HTTP status of the response; errors have theirs even if 200 is sent.
+	Code   int         // Result code: the status for successes, e.g., an ErrorCode value for errors.
+	Error  bool        // Whether the response reports an error.
+	Data   interface{} // Response data or error message.
+	Desc   string      // Debug info, empty in production mode.
+}
+
+// Envelope wraps the data of a JSON response and returns the content type and
+// the value to encode as the body, or nil for no body, e.g., to wrap data in a
+// custom struct:
+//
+//	r.SetEnvelope(func(ctx *invoke.HttpContext, res *invoke.Response) (string, interface{}) {
+//	    return "application/json", MyResult{OK: !res.Error, Payload: res.Data}
+//	})
+type Envelope func(ctx *HttpContext, res *Response) (contentType string, body interface{})
+
+// ResultEnvelope wraps data in a ResponseResult. It is the default envelope.
+func ResultEnvelope(ctx *HttpContext, res *Response) (string, interface{}) {
+	return "application/json", ResponseResult{
+		Code: res.Code,
+		URL:  ctx.Req.URL.Path,
+		Desc: res.Desc,
+		Data: res.Data,
+	}
+}
+
+// NoEnvelope writes data as is.
+func NoEnvelope(ctx *HttpContext, res *Response) (string, interface{}) {
+	return "application/json", res.Data
+}
+
+// Problem is an RFC 7807 problem details object.
+type Problem struct {
+	Type     string      `json:"type"`               // URI of the problem type, about:blank for plain HTTP errors.
+	Title    string      `json:"title"`              // Short summary of the problem type.
+	Status   int         `json:"status"`             // HTTP status code.
+	Detail   string      `json:"detail,omitempty"`   // Explanation of this occurrence of the problem.
+	Instance string      `json:"instance,omitempty"` // Request path.
+	Code     int         `json:"code,omitempty"`     // ErrorCode value.
+	Errors   interface{} `json:"errors,omitempty"`   // Non-string error data, e.g., failing fields.
+	Debug    string      `json:"debug,omitempty"`    // Debug info, empty in production mode.
+}
+
+// ProblemEnvelope writes errors as RFC 7807 application/problem+json and
+// successful responses as their data. As RFC 7807 requires, problems are
+// sent with their HTTP status even if SetErrorStatus is off.
+func ProblemEnvelope(ctx *HttpContext, res *Response) (string, interface{}) {
+	if !res.Error {
+		return "application/json", res.Data
+	}
+	problem := Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(res.Status),
+		Status:   res.Status,
+		Instance: ctx.Req.URL.Path,
+		Code:     res.Code,
+		Debug:    res.Desc,
+	}
+	if detail, ok := res.Data.(string); ok {
+		problem.Detail = detail
+	} else {
+		problem.Errors = res.Data
+	}
+	return "application/problem+json", problem
+}
+
+// SetMode sets whether responses of the router and its host routers include debug info.
+func (r *router) SetMode(mode Mode) {
+	top := r.top()
+	top.mu.Lock()
+	defer top.mu.Unlock()
+
+	top.Mode = mode
+	top.eachHost(func(hr *router) { hr.Mode = mode })
+	top.publish()
+}
+
+// SetEnvelope sets how the router and its host routers wrap JSON responses:
+// ResultEnvelope (the default), NoEnvelope, ProblemEnvelope or a custom Envelope.
+func (r *router) SetEnvelope(envelope Envelope) {
+	top := r.top()
+	top.mu.Lock()
+	defer top.mu.Unlock()
+
+	top.Envelope = envelope
+	top.eachHost(func(hr *router) { hr.Envelope = envelope })
+	top.publish()
+}
+
+// SetErrorStatus sets whether error responses of WriteErrorJSON and the error
+// handler are sent with the HTTP status of the error, e.g., 404 or the
+// ErrorCodeStatus of an ErrorCode, instead of 200, by the router and its host
+// routers.
+func (r *router) SetErrorStatus(errorStatus bool) {
+	top := r.top()
+	top.mu.Lock()
+	defer top.mu.Unlock()
+
+	top.ErrorStatus = errorStatus
+	top.eachHost(func(hr *router) { hr.ErrorStatus = errorStatus })
+	top.publish()
+}
+
+// production reports whether debug info is left out of the response.
+func (ctx *HttpContext) production() bool {
+	return ctx.table != nil && ctx.table.mode == ModeProduction
+}
+
+// writeResponse wraps the response in the router's envelope and writes it.
+// Errors are sent with status 200 unless SetErrorStatus is on or the body is
+// an application/problem+json problem.
+func (ctx *HttpContext) writeResponse(res *Response) {
+	envelope := ResultEnvelope
+	if ctx.table != nil && ctx.table.envelope != nil {
+		envelope = ctx.table.envelope
+	}
+	contentType, body := envelope(ctx, res)
+
+	status := res.Status
+	if res.Error && (ctx.table == nil || !ctx.table.errorStatus) && contentType != "application/problem+json" {
+		status = http.StatusOK
+	}
+
+	ctx.W.Header().Set("Content-Type", contentType)
+	ctx.W.WriteHeader(status)
+	if body != nil {
+		json.NewEncoder(ctx.W).Encode(body)
+	}
+}
@@ -0,0 +1,81 @@
+package invoke
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecoveryFollowsResponseSettings(t *testing.T) {
+	r := NewRouter()
+	r.SetMode(ModeProduction)
+	r.SetErrorStatus(true)
+	r.SetRecoveryHandler(func(ctx *HttpContext, err interface{}) {
+		ctx.WriteErrorJSON(500, "internal error")
+	})
+	r.GET("/boom/:id", func(ctx *HttpContext) { panic("boom") })
+	api := r.Host("api.example.com")
+	api.GET("/boom", func(ctx *HttpContext) { ctx.WriteErrorJSON(ParamError, "bad") })
+
+	for _, target := range []string{"/boom/1", "http://api.example.com/boom"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		if w.Code == 200 || strings.Contains(w.Body.String(), `"desc"`) {
+			t.Errorf("GET %s = %d %s, want an error status without desc", target, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestErrorStatusIsConsistent(t *testing.T) {
+	r := NewRouter()
+	r.GET("/write", func(ctx *HttpContext) { ctx.WriteErrorJSON(AuthError, "no") })
+	r.GET("/return", func(ctx *HttpContext) error { return AuthError })
+
+	for _, errorStatus := range []bool{false, true} {
+		r.SetErrorStatus(errorStatus)
+		want := 200
+		if errorStatus {
+			want = 401
+		}
+		for _, path := range []string{"/write", "/return"} {
+			if code, _ := serveBody(r, "GET", path); code != want {
+				t.Errorf("SetErrorStatus(%v): GET %s = %d, want %d", errorStatus, path, code, want)
+			}
+		}
+	}
+
+	r.SetErrorStatus(false)
+	r.SetEnvelope(ProblemEnvelope)
+	for _, path := range []string{"/write", "/return"} {
+		code, body := serveBody(r, "GET", path)
+		if code != 401 || !strings.Contains(body, `"status":401`) {
+			t.Errorf("problem for GET %s sent with %d: %s", path, code, body)
+		}
+	}
+}
+
+func TestErrorCodeMessageOnlyInDevelopment(t *testing.T) {
+	r := NewRouter()
+	r.GET("/fail", func(ctx *HttpContext) error {
+		return fmt.Errorf("charge card 4111: %w", BizError)
+	})
+	r.GET("/other", func(ctx *HttpContext) error { return errors.New("dial db 10.0.0.5") })
+
+	for _, tt := range []struct {
+		mode Mode
+		path string
+		want string
+	}{
+		{ModeDevelopment, "/fail", `{"code":3000,"url":"/fail","desc":"charge card 4111: BizError","data":"BizError"}`},
+		{ModeProduction, "/fail", `{"code":3000,"url":"/fail","data":"BizError"}`},
+		{ModeDevelopment, "/other", `{"code":7000,"url":"/other","desc":"dial db 10.0.0.5","data":"Internal Server Error"}`},
+		{ModeProduction, "/other", `{"code":7000,"url":"/other","data":"Internal Server Error"}`},
+	} {
+		r.SetMode(tt.mode)
+		if _, body := serveBody(r, "GET", tt.path); strings.TrimSpace(body) != tt.want {
+			t.Errorf("mode %d: GET %s = %s, want %s", tt.mode, tt.path, body, tt.want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package invoke
+
+import (
+	"strings"
+)
+
+// Route represents a registered route.
+type Route struct {
+	Method      string                        `json:"method"`         // HTTP method of the route.
+	Path        string                        `json:"path"`           // Route pattern including the group prefix, e.g., /user/:id.
+	Name        string                        `json:"name,omitempty"` // Name of the route for URL generation.
+	HandlerName string                        `json:"handler"`        // Name of the registered handler function.
+	Handler     func(ctx *HttpContext)        `json:"-"`              // Handler function for the route.
+	Meta        map[string]interface{}        `json:"meta,omitempty"` // Route metadata, e.g., tags, auth scopes or rate class.
+	Before      []func(ctx *HttpContext) bool `json:"-"`              // Route-specific before hooks.
+	After       []func(ctx *HttpContext)      `json:"-"`              // Route-specific after hooks.
+	Middlewares []func(ctx *HttpContext)      `json:"-"`              // Route-specific middleware.
+
+	parts []routePart // Path segments of the pattern.
+	group *router     // Router or group the route was registered on.
+	id    int         // Index of the route's middleware chain in the route table.
+}
+
+// RouteOption configures a route when it is registered.
+type RouteOption func(route *Route)
+
+// WithName names the route so that its URL can be built with router.URL.
+func WithName(name string) RouteOption {
+	return func(route *Route) {
+		route.Name = name
+	}
+}
+
+// WithBefore adds before hooks that run only for the route, after the group hooks.
+// A hook returning false stops the request.
+func WithBefore(hooks ...func(ctx *HttpContext) bool) RouteOption {
+	return func(route *Route) {
+		route.Before = append(route.Before, hooks...)
+	}
+}
+
+// WithAfter adds after hooks that run only for the route, before the group hooks.
+func WithAfter(hooks ...func(ctx *HttpContext)) RouteOption {
+	return func(route *Route) {
+		route.After = append(route.After, hooks...)
+	}
+}
+
+// WithMiddleware adds middleware that runs only for the route, inside the group middleware.
+func WithMiddleware(middleware ...func(ctx *HttpContext)) RouteOption {
+	return func(route *Route) {
+		route.Middlewares = append(route.Middlewares, middleware...)
+	}
+}
+
+// WithMeta attaches metadata to the route. It is available to hooks and
+// handlers through ctx.Meta.
+func WithMeta(key string, value interface{}) RouteOption {
+	return func(route *Route) {
+		if route.Meta == nil {
+			route.Meta = make(map[string]interface{})
+		}
+		route.Meta[key] = value
+	}
+}
+
+// canonicalPath rebuilds the request path for the route, taking static
+// segments from the route pattern and parameter values from ps. Optional
+// segments missing from ps are left out.
+func (route *Route) canonicalPath(ps Params) string {
+	segments := make([]string, 0, len(route.parts))
+	i := 0
+	for _, part := range route.parts {
+		if part.NodeType == Static {
+			segments = append(segments, part.Text)
+			continue
+		}
+		if i >= len(ps) {
+			break
+		}
+		segments = append(segments, ps[i].Value)
+		i++
+	}
+	return "/" + strings.Join(segments, "/")
+}
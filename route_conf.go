@@ -0,0 +1,242 @@
+package invoke
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var (
+	routeHandlers    = make(map[string]Handler)
+	routeMiddlewares = make(map[string]func(ctx *HttpContext))
+	configDecoders   = map[string]func(data []byte, v interface{}) error{".json": json.Unmarshal}
+	routeConfigLock  sync.RWMutex
+)
+
+// RouteConfig is a route table declared in a config file, e.g., routes.json:
+//
+//	{
+//	    "routes": [
+//	        {"method": "GET", "path": "/user/:id", "handler": "user.show", "middleware": ["auth"], "meta": {"scope": "read"}},
+//	        {"methods": ["POST", "PUT"], "path": "/user", "handler": "user.save", "disabled": true}
+//	    ]
+//	}
+type RouteConfig struct {
+	Routes []RouteEntry `json:"routes" yaml:"routes"`
+}
+
+// RouteEntry declares one route of a RouteConfig.
+type RouteEntry struct {
+	Method     string                 `json:"method,omitempty" yaml:"method,omitempty"`         // HTTP method, or ANY for all common methods.
+	Methods    []string               `json:"methods,omitempty" yaml:"methods,omitempty"`       // HTTP methods, instead of Method.
+	Path       string                 `json:"path" yaml:"path"`                                 // Route pattern, e.g., /user/:id.
+	Handler    string                 `json:"handler" yaml:"handler"`                           // Name given to RegisterHandler.
+	Name       string                 `json:"name,omitempty" yaml:"name,omitempty"`             // Route name for URL generation.
+	Middleware []string               `json:"middleware,omitempty" yaml:"middleware,omitempty"` // Names given to RegisterRouteMiddleware or RegisterMiddleware.
+	Meta       map[string]interface{} `json:"meta,omitempty" yaml:"meta,omitempty"`             // Route metadata.
+	Disabled   bool                   `json:"disabled,omitempty" yaml:"disabled,omitempty"`     // Skip the route.
+}
+
+// RouteConfigError lists every problem found in a RouteConfig.
+type RouteConfigError struct {
+	Errors []string
+}
+
+// Error returns the problems, one per line.
+func (e *RouteConfigError) Error() string {
+	return "invalid route config:\n\t" + strings.Join(e.Errors, "\n\t")
+}
+
+// RegisterHandler registers a handler under a name for use in route configs.
+func RegisterHandler(name string, handler Handler) {
+	routeConfigLock.Lock()
+	defer routeConfigLock.Unlock()
+
+	routeHandlers[name] = handler
+}
+
+// RegisterRouteMiddleware registers router middleware under a name for use in
+// route configs. Middleware registered with RegisterMiddleware can be used too.
+func RegisterRouteMiddleware(name string, middleware func(ctx *HttpContext)) {
+	routeConfigLock.Lock()
+	defer routeConfigLock.Unlock()
+
+	routeMiddlewares[name] = middleware
+}
+
+// RegisterConfigDecoder registers the decoder for route config files with the
+// extension, e.g., RegisterConfigDecoder(".yaml", yaml.Unmarshal). JSON is
+// supported out of the box.
+func RegisterConfigDecoder(ext string, decode func(data []byte, v interface{}) error) {
+	routeConfigLock.Lock()
+	defer routeConfigLock.Unlock()
+
+	configDecoders[strings.ToLower(ext)] = decode
+}
+
+// LoadRouteConfig loads a route config from a file, decoded by its extension.
+func LoadRouteConfig(filePath string) (*RouteConfig, error) {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	routeConfigLock.RLock()
+	decode, ok := configDecoders[ext]
+	routeConfigLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no decoder registered for route config %s", filePath)
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var config RouteConfig
+	if err := decode(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to decode route config %s: %v", filePath, err)
+	}
+	return &config, nil
+}
+
+// LoadRoutesFile loads a route config from a file and registers its routes.
+func (r *router) LoadRoutesFile(filePath string) error {
+	config, err := LoadRouteConfig(filePath)
+	if err != nil {
+		return err
+	}
+	return r.LoadRoutes(config)
+}
+
+// LoadRoutes registers the enabled routes of the config. The config is checked
+// first: unknown handler or middleware names, invalid methods or patterns and
+// conflicting routes are all reported in a *RouteConfigError, and no route is
+// registered unless the whole config is valid. The routes are published at
+// once: requests see either none or all of them.
+func (r *router) LoadRoutes(config *RouteConfig) error {
+	type resolved struct {
+		where   string
+		methods []string
+		path    string
+		handler Handler
+		opts    []RouteOption
+	}
+	var routes []resolved
+	var problems []string
+
+	routeConfigLock.RLock()
+	for i, entry := range config.Routes {
+		if entry.Disabled {
+			continue
+		}
+		where := fmt.Sprintf("route %d (%s)", i, entry.Path)
+		route := resolved{where: where, methods: entry.methods(), path: entry.Path}
+		if len(route.methods) == 0 {
+			problems = append(problems, where+": no method")
+		}
+		for _, method := range route.methods {
+			if !validMethod(method) {
+				problems = append(problems, fmt.Sprintf("%s: invalid method %q", where, method))
+			}
+		}
+		if entry.Path == "" {
+			problems = append(problems, where+": no path")
+		}
+		if route.handler = routeHandlers[entry.Handler]; route.handler == nil {
+			problems = append(problems, fmt.Sprintf("%s: unknown handler %q", where, entry.Handler))
+		}
+		for _, name := range entry.Middleware {
+			middleware, ok := routeMiddlewares[name]
+			if !ok {
+				middleware, ok = RegisteredMiddleware(name)
+			}
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s: unknown middleware %q", where, name))
+				continue
+			}
+			route.opts = append(route.opts, WithMiddleware(middleware))
+		}
+		if entry.Name != "" {
+			route.opts = append(route.opts, WithName(entry.Name))
+		}
+		for key, value := range entry.Meta {
+			route.opts = append(route.opts, WithMeta(key, value))
+		}
+		routes = append(routes, route)
+	}
+	routeConfigLock.RUnlock()
+	if len(problems) > 0 {
+		return &RouteConfigError{Errors: problems}
+	}
+
+	// Register all routes under one lock and publish them at once, so that
+	// requests see the whole config or none of it. The trees are changed
+	// copy-on-write, so the router is restored if any route is invalid.
+	top := r.top()
+	top.mu.Lock()
+	defer top.mu.Unlock()
+
+	trees := make(map[string]*TrieNode, len(top.Trees))
+	for method, root := range top.Trees {
+		trees[method] = root
+	}
+	names := make(map[string]*Route, len(top.Names))
+	for name, route := range top.Names {
+		names[name] = route
+	}
+	registered, chains, freeIDs := top.routes, top.chains, append([]int(nil), top.freeIDs...)
+	for _, route := range routes {
+		for _, method := range route.methods {
+			if err := catchPanic(func() { r.addRoute(method, r.Prefix+route.path, route.handler, route.opts, false) }); err != nil {
+				problems = append(problems, route.where+": "+strings.TrimSpace(err.Error()))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		top.Trees, top.Names, top.routes, top.chains, top.freeIDs = trees, names, registered, chains, freeIDs
+		return &RouteConfigError{Errors: problems}
+	}
+	top.publish()
+	return nil
+}
+
+// methods returns the methods of the entry.
+func (entry RouteEntry) methods() []string {
+	methods := entry.Methods
+	if entry.Method != "" {
+		methods = append([]string{entry.Method}, methods...)
+	}
+	var result []string
+	for _, method := range methods {
+		method = strings.ToUpper(strings.TrimSpace(method))
+		if method == "ANY" {
+			result = append(result, anyMethods...)
+		} else {
+			result = append(result, method)
+		}
+	}
+	return result
+}
+
+// validMethod reports whether method is a valid HTTP method token.
+func validMethod(method string) bool {
+	if method == "" {
+		return false
+	}
+	for i := 0; i < len(method); i++ {
+		if c := method[i]; c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// catchPanic runs fn and returns the value it panics with as an error.
+func catchPanic(fn func()) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("%v", e)
+		}
+	}()
+	fn()
+	return nil
+}
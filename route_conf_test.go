@@ -0,0 +1,41 @@
+package invoke
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLoadRoutesIsAtomic(t *testing.T) {
+	RegisterHandler("conf.ok", func(ctx *HttpContext) { ctx.WriteString("ok") })
+	r := NewRouter()
+	r.GET("/taken", func(ctx *HttpContext) {})
+	before := r.load()
+
+	err := r.LoadRoutes(&RouteConfig{Routes: []RouteEntry{
+		{Method: "GET", Path: "/a", Handler: "conf.ok"},
+		{Method: "GET", Path: "/taken", Handler: "conf.ok"},
+	}})
+	var confErr *RouteConfigError
+	if !errors.As(err, &confErr) || len(confErr.Errors) != 1 {
+		t.Fatalf("LoadRoutes = %v, want one conflict", err)
+	}
+	if r.load() != before || len(r.Routes()) != 1 || r.findRoute("GET", "/a") != nil {
+		t.Fatal("invalid config changed the router")
+	}
+	if code, _ := serveBody(r, "GET", "/a"); code != 404 {
+		t.Errorf("GET /a = %d after a rejected config", code)
+	}
+
+	api := r.Group("/api")
+	if err := api.LoadRoutes(&RouteConfig{Routes: []RouteEntry{
+		{Method: "GET", Path: "/a", Handler: "conf.ok"},
+		{Methods: []string{"GET", "POST"}, Path: "/b", Handler: "conf.ok"},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/api/a", "/api/b"} {
+		if _, body := serveBody(r, "GET", path); body != "ok" {
+			t.Errorf("GET %s = %q", path, body)
+		}
+	}
+}
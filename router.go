@@ -5,235 +5,466 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
-	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
-// NodeType represents the type of trie node.
-type NodeType int
+// Router represents a radix-tree-based router.
+type router struct {
+	Trees            map[string]*TrieNode                    `json:"trees"` // Root node of the radix tree of each HTTP method.
+	BeforeHooks      []func(ctx *HttpContext) bool           `json:"-"`     // Global before hooks.
+	AfterHooks       []func(ctx *HttpContext)                `json:"-"`     // Global after hooks.
+	NotFound         func(ctx *HttpContext)                  `json:"-"`     // Handler for 404 Not Found.
+	NotAllowed       func(ctx *HttpContext)                  `json:"-"`     // Handler for 405 Method Not Allowed.
+	Prefix           string                                  // Prefix for the routes in the group.
+	GroupBefore      []func(ctx *HttpContext) bool           // Group-specific before hooks.
+	GroupAfter       []func(ctx *HttpContext)                // Group-specific after hooks.
+	Middlewares      []func(ctx *HttpContext)                `json:"-"` // Global middleware.
+	GroupMiddlewares []func(ctx *HttpContext)                `json:"-"` // Group-specific middleware.
+	RecoveryHandler  func(ctx *HttpContext, err interface{}) // Custom recovery handler
+	ErrorHandler     func(ctx *HttpContext, err error)       `json:"-"` // Handler for errors returned by handlers.
+	Assets           func(ctx *HttpContext) bool             `json:"-"` // Handler for serving static files.
+	CaseSensitive    bool                                    // Match static segments case-sensitively.
+	PathMode         PathMode                                // Handling of trailing slashes and unclean paths.
+	Names            map[string]*Route                       `json:"-"` // Named routes of the router and its groups.
+	Mode             Mode                                    // Whether responses include debug info.
+	Envelope         Envelope                                `json:"-"` // Wrapper of JSON responses, ResultEnvelope if nil.
+	ErrorStatus      bool                                    // Send the HTTP status of errors instead of 200.
+
+	mu       sync.RWMutex               // Guards the route table, hooks and handlers of the router and its groups.
+	root     *router                    // Router that created the group, nil for a top-level router.
+	parent   *router                    // Router or group the group was created from.
+	groups   []*router                  // Groups of the router, in creation order.
+	routes   []*Route                   // Registered routes, in registration order.
+	chains   [][]func(ctx *HttpContext) // Middleware chain of each route, indexed by route id.
+	freeIDs  []int                      // Ids of removed routes, reused by new routes.
+	hosts    []*hostRoute               // Routers for specific hosts, exact hosts first.
+	fallback *router                    // Router that created the host router, nil for others.
+	table    atomic.Value               // Published *routeTable used by ServeHTTP.
+	contexts sync.Pool                  // Pool of HttpContext values reused across requests.
+}
+
+// PathMode controls how request paths that differ from the registered path
+// by trailing slashes, repeated slashes or dot segments are handled.
+type PathMode int
 
 const (
-	Static NodeType = iota // Static node type for regular string nodes.
-	Param                  // Param node type for parameter nodes, e.g., /user/:id.
-	Regex                  // Regex node type for regex pattern nodes, e.g., /product/{regexp}.
+	PathLenient  PathMode = iota // PathLenient cleans the path and ignores trailing slashes.
+	PathStrict                   // PathStrict only matches the path exactly as registered; paths with repeated slashes or dot segments get 404.
+	PathRedirect                 // PathRedirect redirects to the registered path, or else to the cleaned path.
 )
 
-// TrieNode represents a node in the trie.
-type TrieNode struct {
-	Children []*TrieNode            `json:"children"`  // Child nodes of the current node.
-	Handler  func(ctx *HttpContext) `json:"-"`         // Handler function for the node.
-	Level    int                    `json:"level"`     // Depth level of the node in the trie.
-	Pattern  string                 `json:"pattern"`   // Pattern of the node.
-	NodeType NodeType               `json:"node_type"` // Type of the node (Static, Param, Regex).
-	Method   string                 `json:"method"`    // HTTP method associated with the route.
-	FullPath string                 `json:"full_path"` // Full path to the node.
-	Path     string                 `json:"path"`      // Name of the current node.
+var Router = NewRouter()
+
+// NewRouter creates a new router with an empty route table.
+func NewRouter() *router {
+	r := &router{
+		Trees:      make(map[string]*TrieNode),
+		NotFound:   defaultNotFoundHandler,
+		NotAllowed: defaultNotAllowedHandler,
+		Assets:     defaultAssetsHandler,
+		Names:      make(map[string]*Route),
+	}
+	r.contexts.New = func() interface{} {
+		return &HttpContext{}
+	}
+	r.publish()
+	return r
 }
 
-// Router represents a trie-based router.
-type router struct {
-	Root            *TrieNode                               `json:"root"`  // Root node of the trie.
-	Param           map[string]string                       `json:"param"` // Map of route parameters.
-	BeforeHooks     []func(ctx *HttpContext) bool           `json:"-"`     // Global before hooks.
-	AfterHooks      []func(ctx *HttpContext)                `json:"-"`     // Global after hooks.
-	NotFound        func(ctx *HttpContext)                  `json:"-"`     // Handler for 404 Not Found.
-	Prefix          string                                  // Prefix for the routes in the group.
-	GroupBefore     []func(ctx *HttpContext) bool           // Group-specific before hooks.
-	GroupAfter      []func(ctx *HttpContext)                // Group-specific after hooks.
-	RecoveryHandler func(ctx *HttpContext, err interface{}) // Custom recovery handler
-	Assets          func(ctx *HttpContext) bool             `json:"-"` // Handler for serving static files.
+// AddRoute adds a route to the router.
+func (r *router) AddRoute(method, path string, handler Handler, opts ...RouteOption) {
+	top := r.top()
+	top.mu.Lock()
+	defer top.mu.Unlock()
+
+	r.addRoute(method, path, handler, opts, false)
+	top.publish()
 }
 
-var Router = NewRouter()
+// top returns the top-level router, which owns the route table shared by all its groups.
+func (r *router) top() *router {
+	if r.root != nil {
+		return r.root
+	}
+	return r
+}
 
-// NewRouter creates a new router with an empty root node.
-func NewRouter() *router {
-	return &router{
-		Root: &TrieNode{
-			Children: []*TrieNode{},
-			Level:    0,
-			Pattern:  "",
-			NodeType: Static,
-			FullPath: "",
-		},
-		Param:    make(map[string]string),
-		NotFound: defaultNotFoundHandler,
-		Assets:   defaultAssetsHandler,
+// addRoute inserts a route into the radix tree of its method and returns it.
+// If replace is set, a route registered with the same method and path is
+// removed first. The tree is changed on a copy of the nodes on the path of the
+// route, so that the published route table stays untouched until the next
+// publish and nothing is changed if the route is invalid.
+func (r *router) addRoute(method, path string, handler Handler, opts []RouteOption, replace bool) *Route {
+	top := r.top()
+	pattern := r.routePattern(path)
+	fn, err := handlerFunc(handler)
+	if err != nil {
+		panic(fmt.Sprintf("Error: Route '%s' with method '%s' is invalid: %v.\n", path, method, err))
+	}
+	root := &TrieNode{NodeType: Static}
+	if old := top.Trees[method]; old != nil {
+		root = old.clone()
 	}
 
-}
+	segments, optional, err := optionalSegments(strings.Split(pattern[1:], "/"))
+	if err != nil {
+		panic(fmt.Sprintf("Error: Route '%s' with method '%s' is invalid: %v.\n", path, method, err))
+	}
 
-// AddRoute adds a route to the router.
-func (r *router) AddRoute(method, path string, handler func(ctx *HttpContext)) {
-	parts := splitPath(path) // Split the path into parts.
-	curr := r.Root           // Start from the root node.
-
-	for _, part := range parts {
-		nodeType, pattern, paramName := getNodeTypeAndPattern(part) // Determine node type and pattern.
-		found := false
-
-		for _, child := range curr.Children {
-			if child.Pattern == pattern && child.NodeType == nodeType && child.Method == method {
-				curr = child // Move to the matching child node.
-				found = true
-				break
-			}
+	var old *Route
+	if replace {
+		if old = top.findRoute(method, pattern); old != nil {
+			top.unsetRoute(root, old)
 		}
+	}
 
-		if !found {
-			newNode := &TrieNode{
-				Children: []*TrieNode{},
-				Level:    curr.Level + 1,
-				Pattern:  pattern,
-				NodeType: nodeType,
-				FullPath: curr.FullPath + "/" + pattern,
-				Method:   method, // Store the HTTP method.
-			}
-			if nodeType == Regex {
-				newNode.Pattern = paramName + ":" + pattern
-			}
-			curr.Children = append(curr.Children, newNode) // Add new node if not found.
-			curr = newNode
-		}
+	route := &Route{
+		Method:      method,
+		Path:        pattern,
+		HandlerName: funcName(handler),
+		Handler:     fn,
+		group:       r,
 	}
-	if curr.Handler != nil {
+	duplicate := false
+	route.parts, err = root.insertVariants(segments, optional, top.CaseSensitive, func(leaf *TrieNode) {
+		duplicate = duplicate || leaf.Route != nil
+		leaf.Route = route // Assign the route to the leaf node.
+	})
+	if err != nil {
+		panic(fmt.Sprintf("Error: Route '%s' with method '%s' is invalid: %v.\n", path, method, err))
+	}
+	if duplicate {
 		info := fmt.Sprintf("Warning: Route '%s' with method '%s' is already registered.\n", path, method)
 		panic(info)
 	}
-	curr.Handler = handler // Assign the handler to the leaf node.
+
+	for _, opt := range opts {
+		opt(route)
+	}
+	if route.Name != "" {
+		// Routes with the same pattern build the same URL, so they can share a name.
+		if other, ok := top.Names[route.Name]; ok && other != old && other.Path != route.Path {
+			panic(fmt.Sprintf("Warning: Route name '%s' is already used by '%s'.\n", route.Name, other.Path))
+		}
+	}
+
+	if old != nil {
+		top.dropRoute(old)
+	}
+	if _, ok := top.Names[route.Name]; route.Name != "" && !ok {
+		top.Names[route.Name] = route
+	}
+
+	top.Trees[method] = root
+	if n := len(top.freeIDs); n > 0 {
+		// Reuse the id of a removed route on a copy: published tables still use the old chains.
+		route.id, top.freeIDs = top.freeIDs[n-1], top.freeIDs[:n-1]
+		top.chains = append([][]func(ctx *HttpContext){}, top.chains...)
+		top.chains[route.id] = top.chain(route, route.Handler)
+	} else {
+		route.id = len(top.chains)
+		top.chains = append(top.chains, top.chain(route, route.Handler))
+	}
+	top.routes = append(top.routes, route)
+	return route
+}
+
+// routePattern normalizes a route path according to the router's path mode.
+// In lenient mode leading and trailing slashes are dropped; otherwise a
+// trailing slash is kept as part of the route.
+func (r *router) routePattern(path string) string {
+	if r.top().PathMode == PathLenient {
+		return "/" + strings.Trim(path, "/")
+	}
+	return "/" + strings.TrimPrefix(path, "/")
 }
 
 // ServeHTTP handles HTTP requests.
+// All per-request state lives on the request's HttpContext, and requests are
+// matched against the published route table without locking, so a router can
+// serve concurrent requests while routes and hooks are being changed.
+// HttpContext values are pooled: they and their Params must not be used after
+// the handler returns.
 func (r *router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if r.root != nil {
+		r.root.ServeHTTP(w, req) // Groups share the route table of their router.
+		return
+	}
+
+	ctx := r.contexts.Get().(*HttpContext)
+	ctx.reset(w, req)
+	defer r.contexts.Put(ctx)
+	r.load().matchHost(ctx).serve(ctx)
+}
+
+// serve runs the route matching the request of ctx, recovering from panics.
+func (t *routeTable) serve(ctx *HttpContext) {
+	w, req := ctx.W, ctx.Req
 	defer func() {
 		if err := recover(); err != nil {
-			if r.RecoveryHandler == nil {
+			if recovery := t.scope(t.requestPath(req.URL.Path)).recovery; recovery == nil {
 				// Log the error and return a 500 Internal Server Error response
 				http.Error(w, "500 - Internal Server Error", http.StatusInternalServerError)
 			} else {
-				// Use the custom recovery handler if provided
-				r.RecoveryHandler(&HttpContext{W: w, Req: req}, err)
+				// Use the custom recovery handler if provided, with the request's
+				// context so that its responses follow the router's settings.
+				ctx.W = w
+				ctx.Abort()
+				recovery(ctx, err)
 			}
 		}
 	}()
 
-	path := strings.ToLower(req.URL.Path)
-	parts := splitPath(path) // Split the request path into parts.
-	curr := r.Root           // Start from the root node.
-	params := make(map[string]string)
-	method := req.Method
+	ctx.table = t
+	head := t.route(ctx)
+	ctx.Next()
 
-	// Create HttpContext
-	ctx := &HttpContext{
-		W:      w,
-		Req:    req,
-		Params: params,
+	if head != nil {
+		head.finish()
 	}
+}
 
-	// Execute global before hooks
-	for _, hook := range r.BeforeHooks {
-		if !hook(ctx) {
-			return
-		}
+// rebuildChains rebuilds the middleware chain of every route after the hooks
+// or middleware of the router or one of its groups changed.
+func (r *router) rebuildChains() {
+	chains := make([][]func(ctx *HttpContext), len(r.chains))
+	for _, route := range r.routes {
+		chains[route.id] = r.chain(route, route.Handler)
 	}
+	r.chains = chains
+	r.publish()
+}
 
-	// Execute group before hooks
-	for _, hook := range r.GroupBefore {
-		if !hook(ctx) {
-			return
+// chain returns the middleware chain for a request, from the outside in:
+// global middleware and hooks, the group's middleware and hooks, the route's
+// middleware and hooks, and finally handler. Route is nil for unmatched requests.
+func (r *router) chain(route *Route, handler func(ctx *HttpContext)) []func(ctx *HttpContext) {
+	chain := append([]func(ctx *HttpContext){}, r.Middlewares...)
+	chain = appendHooks(chain, r.BeforeHooks, r.AfterHooks)
+	chain = append(chain, r.GroupMiddlewares...)
+	chain = appendHooks(chain, r.GroupBefore, r.GroupAfter)
+	if route != nil {
+		if group := route.group; group != nil && group.root != nil {
+			chain = append(chain, group.GroupMiddlewares...)
+			chain = appendHooks(chain, group.GroupBefore, group.GroupAfter)
 		}
+		chain = append(chain, route.Middlewares...)
+		chain = appendHooks(chain, route.Before, route.After)
 	}
+	return append(chain, handler)
+}
 
-	for _, part := range parts {
-		found := false
-		for _, child := range curr.Children {
-			switch child.NodeType {
-			case Static:
-				if child.Method == method && child.Pattern == part {
-					curr = child
-					found = true
-				}
-			case Param:
-				if child.Method == method {
-					curr = child
-					params[child.Pattern] = part // Add param to the map.
-					found = true
-				}
-			case Regex:
-				//if child.Method == method {
-				patternParts := strings.SplitN(child.Pattern, ":", 2)
-				if child.Method == method && len(patternParts) == 2 {
-					paramName, regexPattern := patternParts[0], patternParts[1]
-					if match := regexp.MustCompile(regexPattern).FindString(part); match == part {
-						curr = child
-						params[paramName] = match // Add param to the map.
-						found = true
-					}
-				}
-				//}
-			}
-			if found {
-				break
-			}
-		}
-		if !found {
-			if !r.Assets(ctx) {
+// appendHooks appends a middleware running the before hooks, the rest of the
+// chain and then the after hooks. A before hook returning false aborts the
+// chain, and the after hooks are skipped when the chain was aborted.
+func appendHooks(chain []func(ctx *HttpContext), before []func(ctx *HttpContext) bool, after []func(ctx *HttpContext)) []func(ctx *HttpContext) {
+	if len(before) == 0 && len(after) == 0 {
+		return chain
+	}
+	return append(chain, func(ctx *HttpContext) {
+		for _, hook := range before {
+			if !hook(ctx) {
+				ctx.Abort()
 				return
 			}
-			r.NotFound(ctx) // Handle 404 Not Found.
+		}
+
+		ctx.Next()
+		if ctx.IsAborted() {
 			return
 		}
-	}
 
-	r.Param = params
-	req = req.WithContext(contextWithParams(req.Context(), params)) // Add params to context.
+		for _, hook := range after {
+			hook(ctx)
+		}
+	})
+}
+
+// headResponseWriter serves HEAD requests with GET handlers. It discards the
+// body and sends the headers, including Content-Length, once the handler returns.
+type headResponseWriter struct {
+	http.ResponseWriter
+	status int // Status code set by the handler.
+	size   int // Number of body bytes discarded.
+}
 
-	if curr.Handler != nil {
-		curr.Handler(ctx)
-	} else {
-		r.NotFound(ctx)
+// WriteHeader records the status code until the response is finished.
+func (w *headResponseWriter) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+}
+
+// Write counts and discards the body.
+func (w *headResponseWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
 	}
+	if w.size == 0 && len(data) > 0 && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", http.DetectContentType(data))
+	}
+	w.size += len(data)
+	return len(data), nil
+}
 
-	// Execute global after hooks
-	for _, hook := range r.AfterHooks {
-		hook(ctx)
+// finish writes the recorded status and headers to the underlying writer.
+func (w *headResponseWriter) finish() {
+	if w.status == 0 {
+		w.status = http.StatusOK
 	}
+	if w.Header().Get("Content-Length") == "" && w.size > 0 {
+		w.Header().Set("Content-Length", strconv.Itoa(w.size))
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
 
-	// Execute group after hooks
-	for _, hook := range r.GroupAfter {
-		hook(ctx)
+// redirect redirects the request to target, keeping the query string.
+// GET and HEAD use 301; other methods use 308 so the method and body are kept.
+func redirect(w http.ResponseWriter, req *http.Request, target string) {
+	code := http.StatusMovedPermanently
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		code = http.StatusPermanentRedirect
 	}
+	if req.URL.RawQuery != "" {
+		target += "?" + req.URL.RawQuery
+	}
+	http.Redirect(w, req, target, code)
 }
 
-// SetRecoveryHandler sets the custom recovery handler.
+// SetRecoveryHandler sets the custom recovery handler. Set on a group, it
+// handles panics of requests under the group's prefix.
 func (r *router) SetRecoveryHandler(handler func(ctx *HttpContext, err interface{})) {
+	top := r.top()
+	top.mu.Lock()
+	defer top.mu.Unlock()
+
 	r.RecoveryHandler = handler
+	top.publish()
 }
 
-// RegisterBeforeHook registers a global before hook.
+// RegisterBeforeHook registers a global before hook. Called on a group, it registers the hook on the group's router.
 func (r *router) RegisterBeforeHook(hook func(ctx *HttpContext) bool) {
-	r.BeforeHooks = append(r.BeforeHooks, hook)
+	top := r.top()
+	top.mu.Lock()
+	defer top.mu.Unlock()
+
+	top.BeforeHooks = append(top.BeforeHooks, hook)
+	top.rebuildChains()
 }
 
-// RegisterAfterHook registers a global after hook.
+// RegisterAfterHook registers a global after hook. Called on a group, it registers the hook on the group's router.
 func (r *router) RegisterAfterHook(hook func(ctx *HttpContext)) {
-	r.AfterHooks = append(r.AfterHooks, hook)
+	top := r.top()
+	top.mu.Lock()
+	defer top.mu.Unlock()
+
+	top.AfterHooks = append(top.AfterHooks, hook)
+	top.rebuildChains()
 }
 
-// SetNotFoundHandler sets the 404 Not Found handler.
+// Use registers global middleware. Middleware wraps the rest of the chain by
+// calling ctx.Next and can stop it with ctx.Abort. It runs outside the hooks.
+func (r *router) Use(middleware ...func(ctx *HttpContext)) {
+	top := r.top()
+	top.mu.Lock()
+	defer top.mu.Unlock()
+
+	top.Middlewares = append(top.Middlewares, middleware...)
+	top.rebuildChains()
+}
+
+// UseGroup registers middleware for the group. It runs inside the global
+// middleware and hooks and outside the group hooks. Called on the top-level
+// router, it applies to all its routes, like RegisterGroupBeforeHook.
+func (r *router) UseGroup(middleware ...func(ctx *HttpContext)) {
+	top := r.top()
+	top.mu.Lock()
+	defer top.mu.Unlock()
+
+	r.GroupMiddlewares = append(r.GroupMiddlewares, middleware...)
+	top.rebuildChains()
+}
+
+// SetCaseSensitive sets whether static path segments are matched case-sensitively.
+// It should be called before routes are registered. Captured parameter values
+// always keep the case of the request path. Called on a group, it applies to
+// the whole router.
+func (r *router) SetCaseSensitive(caseSensitive bool) {
+	top := r.top()
+	top.mu.Lock()
+	defer top.mu.Unlock()
+
+	top.CaseSensitive = caseSensitive
+	top.publish()
+}
+
+// SetPathMode sets how trailing slashes and unclean paths are handled.
+// It should be called before routes are registered. Called on a group, it
+// applies to the whole router.
+func (r *router) SetPathMode(mode PathMode) {
+	top := r.top()
+	top.mu.Lock()
+	defer top.mu.Unlock()
+
+	top.PathMode = mode
+	top.publish()
+}
+
+// SetNotFoundHandler sets the 404 Not Found handler. Set on a group, it
+// handles unmatched requests under the group's prefix.
 func (r *router) SetNotFoundHandler(handler func(ctx *HttpContext)) {
+	top := r.top()
+	top.mu.Lock()
+	defer top.mu.Unlock()
+
 	r.NotFound = handler
+	top.publish()
 }
 
-// SetAssetsHandler sets the handler for serving static files.
+// SetNotAllowedHandler sets the 405 Method Not Allowed handler. Set on a group,
+// it handles requests under the group's prefix.
+// The Allow header is already set when the handler is called.
+func (r *router) SetNotAllowedHandler(handler func(ctx *HttpContext)) {
+	top := r.top()
+	top.mu.Lock()
+	defer top.mu.Unlock()
+
+	r.NotAllowed = handler
+	top.publish()
+}
+
+// SetAssetsHandler sets the handler for serving static files. Set on a group,
+// it serves unmatched requests under the group's prefix.
 func (r *router) SetAssetsHandler(handler func(ctx *HttpContext) bool) {
+	top := r.top()
+	top.mu.Lock()
+	defer top.mu.Unlock()
+
 	r.Assets = handler
+	top.publish()
 }
 
 // defaultAssetsHandler is the default handler for serving static files.
 func defaultAssetsHandler(ctx *HttpContext) bool {
-	filePath := "./" + strings.Trim(ctx.Req.URL.Path, "/")
+	return serveAssets(ctx, ".")
+}
+
+// AssetsDir returns a handler serving static files from dir, e.g., to give a
+// host router its own files with SetAssetsHandler.
+func AssetsDir(dir string) func(ctx *HttpContext) bool {
+	return func(ctx *HttpContext) bool {
+		return serveAssets(ctx, dir)
+	}
+}
+
+// serveAssets serves the static file for the request path from dir.
+func serveAssets(ctx *HttpContext, dir string) bool {
+	filePath := filepath.Join(dir, filepath.FromSlash(path.Clean("/"+ctx.Req.URL.Path))) // Clean to keep ".." inside the directory.
 
 	// If the requested path is a directory, try to serve index.html in that directory.
 	if fileInfo, err := os.Stat(filePath); err == nil && fileInfo.IsDir() {
@@ -266,83 +497,109 @@ func fileExists(filePath string) bool {
 }
 
 // Group creates a new router group with the specified prefix.
+// Unmatched requests and panics under the prefix are handled by the NotFound,
+// NotAllowed, Assets and recovery handlers set on the group, or else on its
+// closest parent that has them; the group with the longest matching prefix wins.
 func (r *router) Group(prefix string) *router {
-	return &router{
-		Root:        r.Root,
-		Param:       r.Param,
-		BeforeHooks: r.BeforeHooks,
-		AfterHooks:  r.AfterHooks,
-		NotFound:    r.NotFound,
-		Prefix:      r.Prefix + prefix,
-		GroupBefore: append([]func(ctx *HttpContext) bool{}, r.GroupBefore...), // Copy hooks from parent group.
-		GroupAfter:  append([]func(ctx *HttpContext){}, r.GroupAfter...),       // Copy hooks from parent group.
+	top := r.top()
+	top.mu.Lock()
+	defer top.mu.Unlock()
+
+	group := &router{
+		root:   top,
+		parent: r,
+		Prefix: r.Prefix + prefix,
 	}
+	if r.root != nil {
+		// The top-level router's group hooks and middleware already run for every route.
+		group.GroupBefore = append([]func(ctx *HttpContext) bool{}, r.GroupBefore...)      // Copy hooks from parent group.
+		group.GroupAfter = append([]func(ctx *HttpContext){}, r.GroupAfter...)             // Copy hooks from parent group.
+		group.GroupMiddlewares = append([]func(ctx *HttpContext){}, r.GroupMiddlewares...) // Copy middleware from parent group.
+	}
+	top.groups = append(top.groups, group)
+	return group
 }
 
 // RegisterGroupBeforeHook registers a before hook for the group.
 func (r *router) RegisterGroupBeforeHook(hook func(ctx *HttpContext) bool) {
+	top := r.top()
+	top.mu.Lock()
+	defer top.mu.Unlock()
+
 	r.GroupBefore = append(r.GroupBefore, hook)
+	top.rebuildChains()
 }
 
 // RegisterGroupAfterHook registers an after hook for the group.
 func (r *router) RegisterGroupAfterHook(hook func(ctx *HttpContext)) {
+	top := r.top()
+	top.mu.Lock()
+	defer top.mu.Unlock()
+
 	r.GroupAfter = append(r.GroupAfter, hook)
+	top.rebuildChains()
 }
 
 // registerRoute registers a route.
-func (r *router) registerRoute(method, path string, handler func(ctx *HttpContext)) {
-	fullPath := r.Prefix + path
-	fullPath = strings.ToLower(fullPath)
-	r.AddRoute(method, fullPath, func(ctx *HttpContext) {
-		// Execute group before hooks
-		for _, hook := range r.GroupBefore {
-			if !hook(ctx) {
-				return
-			}
-		}
-
-		handler(ctx)
-
-		// Execute group after hooks
-		for _, hook := range r.GroupAfter {
-			hook(ctx)
-		}
-	})
+func (r *router) registerRoute(method, path string, handler Handler, opts []RouteOption) {
+	r.AddRoute(method, r.Prefix+path, handler, opts...)
 }
 
 // GET registers a GET route.
-func (r *router) GET(path string, handler func(ctx *HttpContext)) {
-	r.registerRoute("GET", path, handler)
+func (r *router) GET(path string, handler Handler, opts ...RouteOption) {
+	r.registerRoute("GET", path, handler, opts)
 }
 
 // POST registers a POST route.
-func (r *router) POST(path string, handler func(ctx *HttpContext)) {
-	r.registerRoute("POST", path, handler)
+func (r *router) POST(path string, handler Handler, opts ...RouteOption) {
+	r.registerRoute("POST", path, handler, opts)
 }
 
 // DELETE registers a DELETE route.
-func (r *router) DELETE(path string, handler func(ctx *HttpContext)) {
-	r.registerRoute("DELETE", path, handler)
+func (r *router) DELETE(path string, handler Handler, opts ...RouteOption) {
+	r.registerRoute("DELETE", path, handler, opts)
 }
 
 // PUT registers a PUT route.
-func (r *router) PUT(path string, handler func(ctx *HttpContext)) {
-	r.registerRoute("PUT", path, handler)
+func (r *router) PUT(path string, handler Handler, opts ...RouteOption) {
+	r.registerRoute("PUT", path, handler, opts)
 }
 
 // PATCH registers a PATCH route.
-func (r *router) PATCH(path string, handler func(ctx *HttpContext)) {
-	r.registerRoute("PATCH", path, handler)
+func (r *router) PATCH(path string, handler Handler, opts ...RouteOption) {
+	r.registerRoute("PATCH", path, handler, opts)
 }
 
 // HEAD registers a HEAD route.
-func (r *router) HEAD(path string, handler func(ctx *HttpContext)) {
-	r.registerRoute("HEAD", path, handler)
+func (r *router) HEAD(path string, handler Handler, opts ...RouteOption) {
+	r.registerRoute("HEAD", path, handler, opts)
 }
 
 // OPTIONS registers a OPTIONS route.
-func (r *router) OPTIONS(path string, handler func(ctx *HttpContext)) {
-	r.registerRoute("OPTIONS", path, handler)
+func (r *router) OPTIONS(path string, handler Handler, opts ...RouteOption) {
+	r.registerRoute("OPTIONS", path, handler, opts)
+}
+
+// anyMethods are the methods registered by Any.
+var anyMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
+
+// Handle registers the handler for each of the methods.
+func (r *router) Handle(methods []string, path string, handler Handler, opts ...RouteOption) {
+	for _, method := range methods {
+		r.registerRoute(method, path, handler, opts)
+	}
+}
+
+// HandlePaths registers the handler for each of the methods on each of the paths.
+func (r *router) HandlePaths(methods []string, paths []string, handler Handler, opts ...RouteOption) {
+	for _, path := range paths {
+		r.Handle(methods, path, handler, opts...)
+	}
+}
+
+// Any registers the handler for all common methods: GET, POST, PUT, PATCH, DELETE, HEAD and OPTIONS.
+func (r *router) Any(path string, handler Handler, opts ...RouteOption) {
+	r.Handle(anyMethods, path, handler, opts...)
 }
 
 // defaultNotFoundHandler is the default 404 Not Found handler.
@@ -350,26 +607,9 @@ func defaultNotFoundHandler(ctx *HttpContext) {
 	http.Error(ctx.W, "404 - Not Found", http.StatusNotFound)
 }
 
-// splitPath splits the path into parts.
-func splitPath(path string) []string {
-	return strings.Split(strings.Trim(path, "/"), "/")
-}
-
-// getNodeTypeAndPattern determines the node type and pattern.
-func getNodeTypeAndPattern(part string) (NodeType, string, string) {
-	if strings.HasPrefix(part, ":") {
-		return Param, part[1:], ""
-	}
-	if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
-		// Extract the parameter name and regex pattern
-		content := part[1 : len(part)-1]
-		patternParts := strings.SplitN(content, ":", 2)
-		if len(patternParts) < 2 {
-			return Static, part, ""
-		}
-		return Regex, patternParts[1], patternParts[0]
-	}
-	return Static, part, ""
+// defaultNotAllowedHandler is the default 405 Method Not Allowed handler.
+func defaultNotAllowedHandler(ctx *HttpContext) {
+	http.Error(ctx.W, "405 - Method Not Allowed", http.StatusMethodNotAllowed)
 }
 
 // contextKey is a type for context keys to avoid conflicts.
@@ -384,6 +624,11 @@ func contextWithParams(ctx context.Context, params map[string]string) context.Co
 }
 
 // GetParams retrieves the route parameters from the request context.
+//
+// Deprecated: ServeHTTP no longer stores the parameters on ctx.Req, so
+// GetParams(ctx.Req) returns nil inside route handlers; use ctx.Params or
+// ctx.Param instead. The parameters are only in the context of requests
+// returned by ctx.Request and passed to handlers by WrapHandler and Mount.
 func GetParams(req *http.Request) map[string]string {
 	if params, ok := req.Context().Value(ParamsKey).(map[string]string); ok {
 		return params
@@ -0,0 +1,105 @@
+package invoke
+
+import (
+	"net/http"
+	"strings"
+)
+
+// mountParam is the catch-all parameter holding the path below a mount prefix.
+const mountParam = "mountpath"
+
+// Mount serves every request under prefix with handler, which can be any
+// http.Handler such as a file server or another router. The prefix is
+// stripped from the request path before the handler is called, keeping its
+// trailing slash, and the parameters captured by the prefix, e.g.,
+// /tenants/:id, are available through GetParams. Options apply to the routes
+// registered for the prefix. Handlers that route on the full path, such as
+// those of net/http/pprof, are registered with WrapHandler instead.
+func (r *router) Mount(prefix string, handler http.Handler, opts ...RouteOption) {
+	mounted := func(ctx *HttpContext) {
+		req := ctx.Request()
+		u := *req.URL
+		u.Path = "/" + ctx.Param(mountParam)
+		if u.Path != "/" && strings.HasSuffix(req.URL.Path, "/") && !strings.HasSuffix(u.Path, "/") {
+			u.Path += "/" // Lenient matching drops the trailing slash.
+		}
+		u.RawPath = ""
+		req.URL = &u
+		handler.ServeHTTP(ctx.W, req)
+	}
+	r.Handle(anyMethods, strings.TrimSuffix(prefix, "/")+"/*"+mountParam, mounted, opts...)
+}
+
+// WrapHandler adapts an http.Handler to a route handler. The route parameters
+// are available to it through GetParams.
+func WrapHandler(handler http.Handler) func(ctx *HttpContext) {
+	return func(ctx *HttpContext) {
+		handler.ServeHTTP(ctx.W, ctx.Request())
+	}
+}
+
+// WrapMiddleware adapts net/http middleware to router middleware, e.g., for
+// Use or WithMiddleware. The rest of the chain runs when the middleware calls
+// the next handler, with the response writer and request it passes on; if it
+// does not call it, the chain is aborted.
+func WrapMiddleware(middleware func(http.Handler) http.Handler) func(ctx *HttpContext) {
+	return func(ctx *HttpContext) {
+		w, req := ctx.W, ctx.Req
+		called := false
+		next := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			called = true
+			ctx.W, ctx.Req = w, req
+			ctx.Next()
+		})
+		middleware(next).ServeHTTP(w, req)
+		ctx.W, ctx.Req = w, req
+		if !called {
+			ctx.Abort()
+		}
+	}
+}
+
+// RegisteredMiddleware returns the middleware registered with RegisterMiddleware
+// under name, adapted to router middleware with WrapMiddleware.
+func RegisteredMiddleware(name string) (func(ctx *HttpContext), bool) {
+	middleware, ok := middlewares[name]
+	if !ok {
+		return nil, false
+	}
+	return WrapMiddleware(middleware), true
+}
+
+// HTTPHandler adapts a route handler to an http.Handler, e.g., to serve it
+// from http.ServeMux. Parameters stored with contextWithParams, such as those
+// of a Mount prefix, are available through ctx.Params.
+func HTTPHandler(handler func(ctx *HttpContext)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := &HttpContext{W: w, Req: req, Params: paramsFromMap(GetParams(req))}
+		ctx.handlers = []func(ctx *HttpContext){handler}
+		ctx.Next()
+	})
+}
+
+// HTTPMiddleware adapts router middleware to net/http middleware. The rest of
+// the handler chain runs when the middleware calls ctx.Next, or after it
+// returns unless it aborted.
+func HTTPMiddleware(middleware func(ctx *HttpContext)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ctx := &HttpContext{W: w, Req: req, Params: paramsFromMap(GetParams(req))}
+			ctx.handlers = []func(ctx *HttpContext){middleware, func(ctx *HttpContext) {
+				next.ServeHTTP(ctx.W, ctx.Req)
+			}}
+			ctx.Next()
+		})
+	}
+}
+
+// paramsFromMap converts parameters stored in a request context to Params.
+func paramsFromMap(m map[string]string) Params {
+	ps := make(Params, 0, len(m))
+	for key, value := range m {
+		ps = append(ps, PathParam{Key: key, Value: value})
+	}
+	return ps
+}
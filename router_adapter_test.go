@@ -0,0 +1,142 @@
+package invoke
+
+import (
+	"net/http"
+	"net/http/httptest"
+	_ "net/http/pprof"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMountFileServer(t *testing.T) {
+	files := fstest.MapFS{
+		"css/site.css":    {Data: []byte("body{}")},
+		"docs/index.html": {Data: []byte("<h1>docs</h1>")},
+	}
+	for _, mode := range []PathMode{PathLenient, PathStrict, PathRedirect} {
+		r := NewRouter()
+		r.SetPathMode(mode)
+		r.Mount("/static/", http.FileServer(http.FS(files)))
+
+		for path, want := range map[string]string{
+			"/static/css/site.css": "body{}",
+			"/static/docs/":        "<h1>docs</h1>",
+		} {
+			if code, body := serveBody(r, "GET", path); code != 200 || body != want {
+				t.Errorf("mode %d: GET %s = %d %q, want %q", mode, path, code, body, want)
+			}
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/static/docs", nil))
+		if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "docs/" {
+			t.Errorf("mode %d: GET /static/docs = %d to %q, want the file server's redirect to docs/", mode, w.Code, w.Header().Get("Location"))
+		}
+	}
+}
+
+func TestMountRouter(t *testing.T) {
+	sub := NewRouter()
+	sub.GET("/users/:id", func(ctx *HttpContext) {
+		ctx.WriteString(GetParams(ctx.Req)["tenant"] + " " + ctx.Param("id") + " " + ctx.Req.URL.Path)
+	})
+	r := NewRouter()
+	r.Mount("/tenants/:tenant", sub)
+
+	if code, body := serveBody(r, "GET", "/tenants/acme/users/7"); code != 200 || body != "acme 7 /users/7" {
+		t.Errorf("GET /tenants/acme/users/7 = %d %q", code, body)
+	}
+	if code, _ := serveBody(r, "GET", "/tenants/acme/orders"); code != 404 {
+		t.Errorf("GET /tenants/acme/orders = %d, want the sub-router's 404", code)
+	}
+}
+
+func TestWrapHandler(t *testing.T) {
+	r := NewRouter()
+	r.GET("/users/:id", WrapHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(req.URL.Path + " " + GetParams(req)["id"]))
+	})))
+	r.GET("/debug/pprof/*path", WrapHandler(http.DefaultServeMux))
+
+	if _, body := serveBody(r, "GET", "/users/7"); body != "/users/7 7" {
+		t.Errorf("GET /users/7 = %q", body)
+	}
+	if code, body := serveBody(r, "GET", "/debug/pprof/"); code != 200 || !strings.Contains(body, "goroutine") {
+		t.Errorf("GET /debug/pprof/ = %d, want the pprof index", code)
+	}
+	if code, body := serveBody(r, "GET", "/debug/pprof/cmdline"); code != 200 || body == "" {
+		t.Errorf("GET /debug/pprof/cmdline = %d %q", code, body)
+	}
+}
+
+func TestWrapMiddleware(t *testing.T) {
+	tag := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("X-Tag", "on")
+			w.Write([]byte("before "))
+			next.ServeHTTP(w, req)
+			w.Write([]byte(" after"))
+		})
+	}
+	deny := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			http.Error(w, "denied", http.StatusForbidden)
+		})
+	}
+	r := NewRouter()
+	r.GET("/open", func(ctx *HttpContext) { ctx.WriteString("handler") }, WithMiddleware(WrapMiddleware(tag)))
+	r.GET("/closed", func(ctx *HttpContext) { ctx.WriteString("handler") }, WithMiddleware(WrapMiddleware(deny)))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/open", nil))
+	if w.Body.String() != "before handler after" || w.Header().Get("X-Tag") != "on" {
+		t.Errorf("GET /open = %q with X-Tag %q", w.Body.String(), w.Header().Get("X-Tag"))
+	}
+	if code, body := serveBody(r, "GET", "/closed"); code != http.StatusForbidden || strings.Contains(body, "handler") {
+		t.Errorf("GET /closed = %d %q, want the chain aborted", code, body)
+	}
+}
+
+func TestHTTPHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/hello", HTTPHandler(func(ctx *HttpContext) { ctx.WriteString("hello " + ctx.Req.URL.Query().Get("name")) }))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/hello?name=ann", nil))
+	if w.Body.String() != "hello ann" {
+		t.Errorf("GET /hello through ServeMux = %q", w.Body.String())
+	}
+
+	r := NewRouter()
+	r.Mount("/users/:id", HTTPHandler(func(ctx *HttpContext) { ctx.WriteString("user " + ctx.Param("id")) }))
+	if _, body := serveBody(r, "GET", "/users/7/profile"); body != "user 7" {
+		t.Errorf("mounted HTTPHandler params = %q", body)
+	}
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { w.Write([]byte("next")) })
+	tag := HTTPMiddleware(func(ctx *HttpContext) {
+		ctx.WriteString("mw ")
+		ctx.Next()
+		ctx.WriteString(" done")
+	})
+	passive := HTTPMiddleware(func(ctx *HttpContext) { ctx.W.Header().Set("X-Seen", "yes") })
+	deny := HTTPMiddleware(func(ctx *HttpContext) { ctx.AbortWithStatus(http.StatusUnauthorized) })
+
+	for _, tt := range []struct {
+		name string
+		mw   func(http.Handler) http.Handler
+		code int
+		want string
+	}{
+		{"next", tag, 200, "mw next done"},
+		{"after return", passive, 200, "next"},
+		{"abort", deny, http.StatusUnauthorized, ""},
+	} {
+		w := httptest.NewRecorder()
+		tt.mw(next).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != tt.code || w.Body.String() != tt.want {
+			t.Errorf("%s: %d %q, want %d %q", tt.name, w.Code, w.Body.String(), tt.code, tt.want)
+		}
+	}
+}
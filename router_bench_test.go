@@ -0,0 +1,140 @@
+package invoke
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// benchWriter is a ResponseWriter that discards the response.
+type benchWriter struct {
+	header http.Header
+}
+
+func (w *benchWriter) Header() http.Header         { return w.header }
+func (w *benchWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *benchWriter) WriteHeader(statusCode int)  {}
+
+// benchServe serves the request b.N times.
+func benchServe(b *testing.B, h http.Handler, path string) {
+	req := httptest.NewRequest("GET", path, nil)
+	w := &benchWriter{header: http.Header{}}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.ServeHTTP(w, req)
+	}
+}
+
+func benchHandler(ctx *HttpContext) {}
+
+// BenchmarkRegexRoute matches a regex segment compiled at registration.
+func BenchmarkRegexRoute(b *testing.B) {
+	r := NewRouter()
+	r.GET("/product/{id:[0-9]+}", benchHandler)
+	benchServe(b, r, "/product/12345")
+}
+
+// BenchmarkRegexRouteCompilePerRequest serves the same route through the
+// matcher used before regexes were compiled at registration, for comparison
+// with BenchmarkRegexRoute.
+func BenchmarkRegexRouteCompilePerRequest(b *testing.B) {
+	r := &legacyRouter{}
+	r.add("/product/{id:[0-9]+}", func(ctx *HttpContext) {})
+	benchServe(b, r, "/product/12345")
+}
+
+// legacyRouter replicates the original matcher: it lower-cases and splits
+// the path, walks the children linearly and compiles regex segments on
+// every request.
+type legacyRouter struct {
+	root legacyNode
+}
+
+type legacyNode struct {
+	children []*legacyNode
+	handler  func(ctx *HttpContext)
+	nodeType NodeType
+	pattern  string
+}
+
+func (r *legacyRouter) add(path string, handler func(ctx *HttpContext)) {
+	curr := &r.root
+	for _, part := range strings.Split(strings.Trim(strings.ToLower(path), "/"), "/") {
+		node := &legacyNode{nodeType: Static, pattern: part}
+		if strings.HasPrefix(part, ":") {
+			node.nodeType, node.pattern = Param, part[1:]
+		} else if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			node.nodeType, node.pattern = Regex, part[1:len(part)-1]
+		}
+		curr.children = append(curr.children, node)
+		curr = node
+	}
+	curr.handler = handler
+}
+
+func (r *legacyRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	parts := strings.Split(strings.Trim(strings.ToLower(req.URL.Path), "/"), "/")
+	curr := &r.root
+	params := make(map[string]string)
+	ctx := &HttpContext{W: w, Req: req}
+	for _, part := range parts {
+		found := false
+		for _, child := range curr.children {
+			switch child.nodeType {
+			case Static:
+				found = child.pattern == part
+			case Param:
+				params[child.pattern] = part
+				found = true
+			case Regex:
+				patternParts := strings.SplitN(child.pattern, ":", 2)
+				if match := regexp.MustCompile(patternParts[1]).FindString(part); match == part {
+					params[patternParts[0]] = match
+					found = true
+				}
+			}
+			if found {
+				curr = child
+				break
+			}
+		}
+		if !found {
+			http.NotFound(w, req)
+			return
+		}
+	}
+	ctx.Req = req.WithContext(contextWithParams(req.Context(), params))
+	curr.handler(ctx)
+}
+
+// benchRouter returns a router with a mix of static, param, regex, typed and catch-all routes.
+func benchRouter() *router {
+	r := NewRouter()
+	for _, path := range []string{
+		"/", "/about", "/contact", "/users", "/users/new", "/users/:id", "/users/:id/posts",
+		"/users/:id/posts/:post", "/orders/{id:[0-9]+}", "/orders/{id:[0-9]+}/items",
+		"/products/{sku:slug}", "/static/*filepath", "/api/v1/status", "/api/v1/metrics",
+	} {
+		r.GET(path, benchHandler)
+	}
+	return r
+}
+
+func BenchmarkLookupStatic(b *testing.B) {
+	benchServe(b, benchRouter(), "/api/v1/metrics")
+}
+
+func BenchmarkLookupParam(b *testing.B) {
+	benchServe(b, benchRouter(), "/users/42/posts/7")
+}
+
+func BenchmarkLookupRegex(b *testing.B) {
+	benchServe(b, benchRouter(), "/orders/1234/items")
+}
+
+func BenchmarkLookupCatchAll(b *testing.B) {
+	benchServe(b, benchRouter(), "/static/css/site/main.css")
+}
@@ -0,0 +1,180 @@
+package invoke
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// Handler is a route handler accepted by GET, POST and the other registration
+// methods. It is one of:
+//
+//	func(ctx *HttpContext)                      // Writes its own response.
+//	func(ctx *HttpContext) error                // Returned errors go to the error handler.
+//	func(ctx *HttpContext, in In) (Out, error)  // Typed handler.
+//	func(ctx *HttpContext, in In) error         // Typed handler without a result.
+//	http.Handler or func(http.ResponseWriter, *http.Request)
+//
+// A typed handler receives In, a struct or a pointer to one, bound with Bind
+// and checked with Validate; binding and validation errors go to the error
+// handler without calling it. Out is written like WriteSuccessJSON does, with
+// the router's envelope, but without the caller info in Desc.
+type Handler interface{}
+
+var (
+	contextType = reflect.TypeOf((*HttpContext)(nil))
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// handlerFunc converts a Handler to the func run at the end of the middleware chain.
+func handlerFunc(handler Handler) (func(ctx *HttpContext), error) {
+	switch h := handler.(type) {
+	case func(ctx *HttpContext):
+		return h, nil
+	case func(ctx *HttpContext) error:
+		return func(ctx *HttpContext) {
+			if err := h(ctx); err != nil {
+				ctx.HandleError(err)
+			}
+		}, nil
+	case func(w http.ResponseWriter, req *http.Request):
+		return WrapHandler(http.HandlerFunc(h)), nil
+	case http.Handler:
+		return WrapHandler(h), nil
+	}
+
+	fn := reflect.ValueOf(handler)
+	if fn.Kind() != reflect.Func || fn.IsNil() {
+		return nil, fmt.Errorf("unsupported handler type %T", handler)
+	}
+	ft := fn.Type()
+	if ft.NumIn() != 2 || ft.In(0) != contextType || ft.NumOut() < 1 || ft.NumOut() > 2 || ft.Out(ft.NumOut()-1) != errorType {
+		return nil, fmt.Errorf("unsupported handler type %T", handler)
+	}
+	in := ft.In(1)
+	if in.Kind() == reflect.Ptr {
+		in = in.Elem()
+	}
+	if in.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("typed handler input %s is not a struct", ft.In(1))
+	}
+	if err := checkValidateTags(in); err != nil {
+		return nil, fmt.Errorf("typed handler input %s: %v", ft.In(1), err)
+	}
+	return typedHandler(fn, in, ft.In(1).Kind() == reflect.Ptr), nil
+}
+
+// typedHandler binds and validates the input of fn, a typed handler, calls it
+// and writes its result or error.
+func typedHandler(fn reflect.Value, in reflect.Type, pointer bool) func(ctx *HttpContext) {
+	return func(ctx *HttpContext) {
+		arg := reflect.New(in)
+		err := ctx.Bind(arg.Interface())
+		if err == nil {
+			err = Validate(arg.Interface())
+		}
+		if err != nil {
+			ctx.HandleError(err)
+			return
+		}
+		if !pointer {
+			arg = arg.Elem()
+		}
+
+		out := fn.Call([]reflect.Value{reflect.ValueOf(ctx), arg})
+		if err, _ := out[len(out)-1].Interface().(error); err != nil {
+			ctx.HandleError(err)
+			return
+		}
+		if len(out) == 2 {
+			ctx.writeResponse(&Response{Status: http.StatusOK, Code: http.StatusOK, Data: out[0].Interface()})
+		}
+	}
+}
+
+// ErrorCodeStatus maps ErrorCode values to the HTTP status codes sent by
+// DefaultErrorHandler.
+var ErrorCodeStatus = map[ErrorCode]int{
+	AuthError:  http.StatusUnauthorized,
+	ParamError: http.StatusBadRequest,
+	BizError:   http.StatusUnprocessableEntity,
+	NetError:   http.StatusBadGateway,
+	DBError:    http.StatusInternalServerError,
+	IOError:    http.StatusInternalServerError,
+	OtherError: http.StatusInternalServerError,
+}
+
+// SetErrorHandler sets the handler for errors returned by handlers, which
+// defaults to DefaultErrorHandler. Set on a group, it handles errors of
+// requests under the group's prefix.
+func (r *router) SetErrorHandler(handler func(ctx *HttpContext, err error)) {
+	top := r.top()
+	top.mu.Lock()
+	defer top.mu.Unlock()
+
+	r.ErrorHandler = handler
+	top.publish()
+}
+
+// HandleError writes the response for an error with the error handler of the
+// router or group serving the request, or with DefaultErrorHandler.
+func (ctx *HttpContext) HandleError(err error) {
+	if t := ctx.table; t != nil {
+		if handler := t.scope(t.requestPath(ctx.Req.URL.Path)).errors; handler != nil {
+			handler(ctx, err)
+			return
+		}
+	}
+	DefaultErrorHandler(ctx, err)
+}
+
+// DefaultErrorHandler maps an error to a status code and a response with an
+// ErrorCode, wrapped by the router's envelope. Like WriteErrorJSON, it sends
+// the status only if SetErrorStatus is on or with ProblemEnvelope, and 200
+// otherwise:
+//
+//   - *BindError and *ValidationError: 400 with ParamError and the failing fields.
+//   - sql.ErrNoRows: 404 with DBError.
+//   - ErrorCode, also wrapped: the status in ErrorCodeStatus with the code name.
+//   - Any other error: 500 with OtherError.
+//
+// The message of ErrorCode and other errors is only included, in Desc, in
+// development mode.
+func DefaultErrorHandler(ctx *HttpContext, err error) {
+	var bindErr *BindError
+	var validationErr *ValidationError
+	var code ErrorCode
+	switch {
+	case errors.As(err, &bindErr):
+		ctx.writeErrorResult(http.StatusBadRequest, ParamError, bindErr.Fields)
+	case errors.As(err, &validationErr):
+		ctx.writeErrorResult(http.StatusBadRequest, ParamError, validationErr.Fields)
+	case errors.Is(err, sql.ErrNoRows):
+		ctx.writeErrorResult(http.StatusNotFound, DBError, http.StatusText(http.StatusNotFound))
+	case errors.As(err, &code):
+		status, ok := ErrorCodeStatus[code]
+		if !ok {
+			status = http.StatusInternalServerError
+		}
+		ctx.writeErrorDesc(status, code, code.String(), err)
+	default:
+		ctx.writeErrorDesc(http.StatusInternalServerError, OtherError, http.StatusText(http.StatusInternalServerError), err)
+	}
+}
+
+// writeErrorDesc writes an error response like writeErrorResult, with the
+// error message in Desc in development mode.
+func (ctx *HttpContext) writeErrorDesc(status int, code ErrorCode, data interface{}, err error) {
+	response := &Response{Status: status, Code: int(code), Error: true, Data: data}
+	if !ctx.production() {
+		response.Desc = err.Error()
+	}
+	ctx.writeResponse(response)
+}
+
+// writeErrorResult writes an error response with the router's envelope.
+func (ctx *HttpContext) writeErrorResult(status int, code ErrorCode, data interface{}) {
+	ctx.writeResponse(&Response{Status: status, Code: int(code), Error: true, Data: data})
+}
@@ -0,0 +1,109 @@
+package invoke
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type orderInput struct {
+	UserID int64  `path:"id"`
+	Item   string `json:"item" validate:"required"`
+	Qty    int    `json:"qty" validate:"min=1"`
+}
+
+type orderOutput struct {
+	UserID int64  `json:"user_id"`
+	Item   string `json:"item"`
+	Qty    int    `json:"qty"`
+}
+
+// postJSON serves a POST request with a JSON body and decodes the ResponseResult.
+func postJSON(t *testing.T, r *router, target, body string) (int, ResponseResult) {
+	t.Helper()
+	req := httptest.NewRequest("POST", target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var res ResponseResult
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("POST %s: invalid JSON %q", target, w.Body.String())
+	}
+	return w.Code, res
+}
+
+func TestTypedHandler(t *testing.T) {
+	r := NewRouter()
+	r.SetErrorStatus(true)
+	r.POST("/users/:id/orders", func(ctx *HttpContext, in orderInput) (orderOutput, error) {
+		return orderOutput{UserID: in.UserID, Item: in.Item, Qty: in.Qty}, nil
+	})
+	r.POST("/users/:id/missing", func(ctx *HttpContext, in *orderInput) error {
+		return sql.ErrNoRows
+	})
+
+	code, res := postJSON(t, r, "/users/7/orders", `{"item":"book","qty":2}`)
+	data, _ := json.Marshal(res.Data)
+	if code != 200 || res.Code != 200 || string(data) != `{"item":"book","qty":2,"user_id":7}` {
+		t.Errorf("typed handler response = %d %+v", code, res)
+	}
+	if res.Desc != "" {
+		t.Errorf("typed handler response Desc = %q, want none", res.Desc)
+	}
+
+	code, res = postJSON(t, r, "/users/7/orders", `{"qty":0}`)
+	data, _ = json.Marshal(res.Data)
+	var fields []FieldError
+	json.Unmarshal(data, &fields)
+	if code != http.StatusBadRequest || res.Code != int(ParamError) || len(fields) != 2 || fields[0].Field != "Item" || fields[1].Field != "Qty" {
+		t.Errorf("invalid input = %d %+v, want 400 with Item and Qty", code, res)
+	}
+
+	code, res = postJSON(t, r, "/users/x/orders", `{"item":"book","qty":1}`)
+	if code != http.StatusBadRequest || res.Code != int(ParamError) {
+		t.Errorf("unbindable input = %d %+v, want 400", code, res)
+	}
+
+	code, res = postJSON(t, r, "/users/7/missing", `{"item":"book","qty":1}`)
+	if code != http.StatusNotFound || res.Code != int(DBError) {
+		t.Errorf("sql.ErrNoRows = %d %+v, want 404", code, res)
+	}
+}
+
+func TestGroupErrorHandler(t *testing.T) {
+	r := NewRouter()
+	admin := r.Group("/admin")
+	admin.SetErrorHandler(func(ctx *HttpContext, err error) {
+		ctx.AbortWithStatus(http.StatusTeapot)
+		ctx.WriteString("admin: " + err.Error())
+	})
+	failing := func(ctx *HttpContext, in orderInput) (orderOutput, error) {
+		return orderOutput{}, errors.New("failed")
+	}
+	admin.POST("/orders", failing)
+	r.POST("/orders", failing)
+
+	req := httptest.NewRequest("POST", "/admin/orders", strings.NewReader(`{"item":"book","qty":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusTeapot || w.Body.String() != "admin: failed" {
+		t.Errorf("group error handler = %d %q", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest("POST", "/admin/orders", strings.NewReader(`{"qty":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if !strings.HasPrefix(w.Body.String(), "admin: validation failed") {
+		t.Errorf("group error handler for validation = %q", w.Body.String())
+	}
+
+	if _, res := postJSON(t, r, "/orders", `{"item":"book","qty":1}`); res.Code != int(OtherError) {
+		t.Errorf("router error handler = %+v, want DefaultErrorHandler's OtherError", res)
+	}
+}
@@ -0,0 +1,185 @@
+package invoke
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// hostRoute routes requests for a host pattern to its own router.
+type hostRoute struct {
+	Pattern string      // Host pattern, e.g., api.example.com or {tenant}.example.com.
+	labels  []hostLabel // Dot-separated labels of the pattern.
+	router  *router     // Router serving the host.
+}
+
+// hostLabel is one dot-separated label of a host pattern.
+type hostLabel struct {
+	Text   string         // Static text, or the parameter name.
+	Param  bool           // Whether the label captures a parameter.
+	Regexp *regexp.Regexp // Compiled pattern of {name:pattern} labels.
+}
+
+// Host returns the router for requests whose Host header matches the pattern,
+// creating it on first use. Labels of the form {name} or {name:pattern}
+// capture the label into ctx.Params, e.g., {tenant}.example.com. Exact hosts
+// are tried before patterns with parameters, and requests for other hosts are
+// served by the routes of r itself.
+//
+// A host router has its own routes, hooks and middleware. It starts with the
+// case sensitivity and path mode of r, and follows the response settings of r
+// as they change unless it sets its own afterwards. The NotFound, NotAllowed,
+// recovery and error handlers it does not set are those of r, and it serves
+// no static files until SetAssetsHandler is called on it.
+func (r *router) Host(pattern string) *router {
+	top := r.top()
+	top.mu.Lock()
+	defer top.mu.Unlock()
+
+	pattern = strings.ToLower(pattern)
+	for _, host := range top.hosts {
+		if host.Pattern == pattern {
+			return host.router
+		}
+	}
+
+	labels, err := parseHostPattern(pattern)
+	if err != nil {
+		panic(fmt.Sprintf("Error: Host '%s' is invalid: %v.\n", pattern, err))
+	}
+	hr := NewRouter()
+	hr.fallback = top
+	hr.CaseSensitive = top.CaseSensitive
+	hr.PathMode = top.PathMode
+	hr.NotFound, hr.NotAllowed = nil, nil // Taken from top when published.
+	hr.Mode, hr.Envelope, hr.ErrorStatus = top.Mode, top.Envelope, top.ErrorStatus
+	hr.Assets = noAssetsHandler
+	hr.publish() // Not shared yet, so mu need not be held.
+
+	host := &hostRoute{Pattern: pattern, labels: labels, router: hr}
+	i := len(top.hosts)
+	if !host.hasParams() {
+		i = 0
+		for i < len(top.hosts) && !top.hosts[i].hasParams() {
+			i++
+		}
+	}
+	// Build a new list: the published route table still uses the old one.
+	hosts := make([]*hostRoute, 0, len(top.hosts)+1)
+	hosts = append(hosts, top.hosts[:i]...)
+	hosts = append(hosts, host)
+	top.hosts = append(hosts, top.hosts[i:]...)
+	top.publish()
+	return hr
+}
+
+// eachHost calls fn for each host router of r with the host router's mu held.
+// It must be called with mu held.
+func (r *router) eachHost(fn func(hr *router)) {
+	for _, host := range r.hosts {
+		host.router.mu.Lock()
+		fn(host.router)
+		host.router.mu.Unlock()
+	}
+}
+
+// parseHostPattern splits a host pattern into its labels.
+func parseHostPattern(pattern string) ([]hostLabel, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("empty host")
+	}
+	var labels []hostLabel
+	for _, text := range strings.Split(pattern, ".") {
+		if !strings.HasPrefix(text, "{") || !strings.HasSuffix(text, "}") {
+			labels = append(labels, hostLabel{Text: text})
+			continue
+		}
+		name, expr := text[1:len(text)-1], ""
+		if i := strings.IndexByte(name, ':'); i >= 0 {
+			name, expr = name[:i], name[i+1:]
+		}
+		if name == "" {
+			return nil, fmt.Errorf("label '%s' has no parameter name", text)
+		}
+		label := hostLabel{Text: name, Param: true}
+		if expr != "" {
+			re, err := compileSegmentRegex(expr, false)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regex label '%s': %v", text, err)
+			}
+			label.Regexp = re
+		}
+		labels = append(labels, label)
+	}
+	return labels, nil
+}
+
+// hasParams reports whether the host pattern captures parameters.
+func (h *hostRoute) hasParams() bool {
+	for _, label := range h.labels {
+		if label.Param {
+			return true
+		}
+	}
+	return false
+}
+
+// match reports whether the host matches the pattern, appending the captured
+// labels to ps. The host is compared ignoring case.
+func (h *hostRoute) match(host string, ps *Params) bool {
+	saved := len(*ps)
+	for i, label := range h.labels {
+		text := host
+		if i < len(h.labels)-1 {
+			end := strings.IndexByte(host, '.')
+			if end < 0 {
+				*ps = (*ps)[:saved]
+				return false
+			}
+			text, host = host[:end], host[end+1:]
+		} else if strings.IndexByte(host, '.') >= 0 {
+			*ps = (*ps)[:saved]
+			return false
+		}
+
+		switch {
+		case !label.Param:
+			if !strings.EqualFold(text, label.Text) {
+				*ps = (*ps)[:saved]
+				return false
+			}
+		case text == "" || (label.Regexp != nil && !label.Regexp.MatchString(text)):
+			*ps = (*ps)[:saved]
+			return false
+		default:
+			*ps = append(*ps, PathParam{Key: label.Text, Value: text})
+		}
+	}
+	return true
+}
+
+// matchHost returns the route table for the Host header of the request, adding
+// the captured host labels to ctx.Params. It returns t if no host pattern matches.
+func (t *routeTable) matchHost(ctx *HttpContext) *routeTable {
+	if len(t.hosts) == 0 {
+		return t
+	}
+	host := ctx.Req.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(host, ".")
+	for _, h := range t.hosts {
+		if h.match(host, &ctx.Params) {
+			return h.router.load()
+		}
+	}
+	return t
+}
+
+// noAssetsHandler serves no static files. Host routers use it so that the
+// static files of the main router are not served for every host.
+func noAssetsHandler(ctx *HttpContext) bool {
+	return true
+}
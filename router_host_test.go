@@ -0,0 +1,84 @@
+package invoke
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHostServesNoAssetsByDefault(t *testing.T) {
+	r := NewRouter()
+	r.SetCaseSensitive(true)
+	hr := r.Host("api.example.com")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "http://api.example.com/router_host_test.go", nil))
+	if w.Code != 404 {
+		t.Errorf("host router without routes served a file: %d", w.Code)
+	}
+	if !hr.load().caseSensitive {
+		t.Error("host router table does not have the router's settings")
+	}
+}
+
+func TestHostFollowsResponseSettings(t *testing.T) {
+	r := NewRouter()
+	hr := r.Host("api.example.com")
+	hr.GET("/settings", func(ctx *HttpContext) {
+		ctx.WriteString(fmt.Sprint(ctx.production(), ctx.table.errorStatus, ctx.table.envelope != nil))
+	})
+
+	r.SetMode(ModeProduction)
+	r.SetErrorStatus(true)
+	r.SetEnvelope(NoEnvelope)
+	if _, body := serveBody(r, "GET", "http://api.example.com/settings"); body != "true true true" {
+		t.Errorf("host settings after SetMode, SetErrorStatus and SetEnvelope = %q", body)
+	}
+
+	hr.SetMode(ModeDevelopment)
+	if _, body := serveBody(r, "GET", "http://api.example.com/settings"); body != "false true true" {
+		t.Errorf("host settings after its own SetMode = %q", body)
+	}
+	if r.Mode != ModeProduction {
+		t.Error("SetMode on the host router changed the main router")
+	}
+}
+
+func TestHostFallsBackToRouterHandlers(t *testing.T) {
+	r := NewRouter()
+	hr := r.Host("api.example.com")
+	hr.GET("/fail", func(ctx *HttpContext) error { return errors.New("failed") })
+	hr.GET("/panic", func(ctx *HttpContext) { panic("boom") })
+	hr.POST("/only-post", func(ctx *HttpContext) {})
+	admin := hr.Group("/admin")
+	admin.SetNotAllowedHandler(func(ctx *HttpContext) { ctx.AbortWithStatus(418) })
+	admin.GET("/x", func(ctx *HttpContext) {})
+
+	r.SetNotFoundHandler(func(ctx *HttpContext) { ctx.AbortWithStatus(410) })
+	r.SetNotAllowedHandler(func(ctx *HttpContext) { ctx.AbortWithStatus(409) })
+	r.SetErrorHandler(func(ctx *HttpContext, err error) { ctx.WriteString("main: " + err.Error()) })
+	r.SetRecoveryHandler(func(ctx *HttpContext, err interface{}) { ctx.WriteString(fmt.Sprint("recovered: ", err)) })
+
+	for _, tt := range []struct{ method, path, want string }{
+		{"GET", "/nope", "410 "},
+		{"GET", "/admin/nope", "410 "},
+		{"GET", "/only-post", "409 "},
+		{"POST", "/admin/x", "418 "},
+		{"GET", "/fail", "200 main: failed"},
+		{"GET", "/panic", "200 recovered: boom"},
+	} {
+		code, body := serveBody(r, tt.method, "http://api.example.com"+tt.path)
+		if got := fmt.Sprint(code, " ", body); got != tt.want {
+			t.Errorf("%s %s on the host = %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+
+	hr.SetNotFoundHandler(func(ctx *HttpContext) { ctx.AbortWithStatus(451) })
+	if code, _ := serveBody(r, "GET", "http://api.example.com/nope"); code != 451 {
+		t.Errorf("GET /nope on the host = %d, want its own 451", code)
+	}
+	if code, _ := serveBody(r, "GET", "http://other.example.com/nope"); code != 410 {
+		t.Errorf("GET /nope on another host = %d, want the main router's 410", code)
+	}
+}
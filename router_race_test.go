@@ -0,0 +1,66 @@
+package invoke
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// TestConcurrentServeAndRegister serves requests from many goroutines while
+// others register and remove routes and change hooks and middleware. Run it
+// with go test -race.
+func TestConcurrentServeAndRegister(t *testing.T) {
+	const readers, writers, requests = 32, 8, 300
+
+	r := NewRouter()
+	g := r.Group("/g")
+	r.GET("/user/:id", func(ctx *HttpContext) {
+		ctx.Set("id", ctx.Param("id"))
+		ctx.WriteString(ctx.Param("id"))
+	})
+	g.GET("/item/{n:int}", func(ctx *HttpContext) { ctx.WriteString(ctx.Param("n")) })
+
+	var wg sync.WaitGroup
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < requests; j++ {
+				id := fmt.Sprintf("%d-%d", i, j)
+				if code, body := serveBody(r, "GET", "/user/"+id); code != 200 || body != id {
+					t.Errorf("GET /user/%s = %d %q", id, code, body)
+					return
+				}
+				n := fmt.Sprint(i*requests + j)
+				if code, body := serveBody(r, "GET", "/g/item/"+n); code != 200 || body != n {
+					t.Errorf("GET /g/item/%s = %d %q", n, code, body)
+					return
+				}
+				serveBody(r, "GET", fmt.Sprintf("/g/w%d/%d", j%writers, j))
+			}
+		}(i)
+	}
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < requests/10; j++ {
+				path := fmt.Sprintf("/w%d/%d", i, j)
+				g.GET(path, func(ctx *HttpContext) {})
+				if j%2 == 0 && !g.RemoveRoute("GET", "/g"+path) {
+					t.Errorf("route %s was not removed", path)
+				}
+				g.RegisterGroupBeforeHook(func(ctx *HttpContext) bool { return true })
+				r.RegisterAfterHook(func(ctx *HttpContext) {})
+				r.Use(func(ctx *HttpContext) { ctx.Next() })
+				g.SetNotFoundHandler(func(ctx *HttpContext) { ctx.AbortWithStatus(404) })
+				_ = r.Routes()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if got := len(r.Routes()); got != 2+writers*requests/20 {
+		t.Errorf("%d routes registered, want %d", got, 2+writers*requests/20)
+	}
+}
@@ -0,0 +1,112 @@
+package invoke
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// RouteInfo describes a registered route.
+type RouteInfo struct {
+	Name      string                 `json:"name,omitempty"` // Name of the route, if any.
+	Method    string                 `json:"method"`         // HTTP method of the route.
+	Path      string                 `json:"path"`           // Full route pattern, e.g., /user/:id.
+	Params    []string               `json:"params"`         // Names of the captured parameters, in path order.
+	NodeTypes []string               `json:"node_types"`     // Type of each path segment.
+	Handler   string                 `json:"handler"`        // Name of the handler function.
+	Meta      map[string]interface{} `json:"meta,omitempty"` // Route metadata.
+}
+
+// String returns the name of the node type.
+func (t NodeType) String() string {
+	switch t {
+	case Static:
+		return "Static"
+	case Param:
+		return "Param"
+	case Regex:
+		return "Regex"
+	case CatchAll:
+		return "CatchAll"
+	}
+	return "Unknown"
+}
+
+// Routes returns all registered routes sorted by path and method.
+func (r *router) Routes() []RouteInfo {
+	top := r.top()
+	top.mu.RLock()
+	defer top.mu.RUnlock()
+
+	routes := make([]RouteInfo, 0, len(top.routes))
+	for _, route := range top.routes {
+		info := RouteInfo{
+			Name:      route.Name,
+			Method:    route.Method,
+			Path:      route.Path,
+			Params:    []string{},
+			NodeTypes: make([]string, 0, len(route.parts)),
+			Handler:   route.HandlerName,
+			Meta:      route.Meta,
+		}
+		for _, part := range route.parts {
+			if part.NodeType != Static {
+				info.Params = append(info.Params, part.Text)
+			}
+			info.NodeTypes = append(info.NodeTypes, part.NodeType.String())
+		}
+		routes = append(routes, info)
+	}
+
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+// RoutesJSON returns the route table as indented JSON.
+func (r *router) RoutesJSON() ([]byte, error) {
+	return json.MarshalIndent(r.Routes(), "", "    ")
+}
+
+// WriteRoutes writes the route table as aligned text, one route per line.
+func (r *router) WriteRoutes(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATH\tPARAMS\tNAME\tHANDLER")
+	for _, route := range r.Routes() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", route.Method, route.Path, strings.Join(route.Params, ","), route.Name, route.Handler)
+	}
+	return tw.Flush()
+}
+
+// ServeRoutes writes the route table as JSON. It can be registered as a debug
+// endpoint, e.g., r.GET("/debug/routes", r.ServeRoutes).
+func (r *router) ServeRoutes(ctx *HttpContext) {
+	data, err := r.RoutesJSON()
+	if err != nil {
+		ctx.WriteErrorJSON(OtherError, err.Error())
+		return
+	}
+	ctx.Header().Set("Content-Type", "application/json")
+	ctx.WriteByte(data)
+}
+
+// funcName returns the name of the function fn, or the type of other handlers.
+func funcName(fn interface{}) string {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func {
+		return reflect.TypeOf(fn).String()
+	}
+	if f := runtime.FuncForPC(v.Pointer()); f != nil {
+		return f.Name()
+	}
+	return "unknown"
+}
@@ -0,0 +1,464 @@
+package invoke
+
+import (
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+)
+
+// routeTable is a snapshot of the routes and handlers of a router, published
+// with publish after every change. ServeHTTP matches requests against the
+// snapshot without locking; it is never modified once published.
+type routeTable struct {
+	trees         map[string]*TrieNode       // Root node of the radix tree of each HTTP method.
+	chains        [][]func(ctx *HttpContext) // Middleware chain of each route, indexed by route id.
+	unmatched     []func(ctx *HttpContext)   // Middleware chain for requests that match no route.
+	hosts         []*hostRoute               // Routers for specific hosts.
+	scopes        []*scope                   // Handlers by path prefix, longest prefix first, ending with the router's own.
+	caseSensitive bool                       // Match static segments case-sensitively.
+	pathMode      PathMode                   // Handling of trailing slashes and unclean paths.
+	mode          Mode                       // Whether responses include debug info.
+	envelope      Envelope                   // Wrapper of JSON responses, nil for ResultEnvelope.
+	errorStatus   bool                       // Send the HTTP status of errors instead of 200.
+}
+
+// scope holds the handlers for unmatched requests, panics and errors under a path prefix.
+type scope struct {
+	prefix     string                                  // Path prefix without trailing slash, "" for the whole router.
+	notFound   func(ctx *HttpContext)                  // Handler for 404 Not Found.
+	notAllowed func(ctx *HttpContext)                  // Handler for 405 Method Not Allowed.
+	assets     func(ctx *HttpContext) bool             // Handler for serving static files.
+	recovery   func(ctx *HttpContext, err interface{}) // Custom recovery handler.
+	errors     func(ctx *HttpContext, err error)       // Handler for errors returned by handlers.
+}
+
+// publish makes the current routes, hooks and handlers of the router visible
+// to ServeHTTP by storing a new route table. It must be called with mu held.
+// The trees are shared with the router: changes to them copy the nodes they
+// touch, so the published nodes are never modified.
+func (r *router) publish() {
+	t := &routeTable{
+		trees:         make(map[string]*TrieNode, len(r.Trees)),
+		chains:        r.chains,
+		hosts:         r.hosts,
+		scopes:        r.scopes(),
+		caseSensitive: r.CaseSensitive,
+		pathMode:      r.PathMode,
+		mode:          r.Mode,
+		envelope:      r.Envelope,
+		errorStatus:   r.ErrorStatus,
+	}
+	for method, root := range r.Trees {
+		t.trees[method] = root
+	}
+	t.unmatched = r.chain(nil, t.serveUnmatched)
+	r.table.Store(t)
+
+	// Host routers take the handlers they do not set from r.
+	r.eachHost(func(hr *router) { hr.publish() })
+}
+
+// scopes returns the handler scopes of the router's groups that set their own
+// handlers, longest prefix first, followed by the router's own handlers. A
+// handler a group does not set is taken from its closest parent, and one a
+// host router does not set from the router it was created from.
+func (r *router) scopes() []*scope {
+	own := &scope{notFound: r.NotFound, notAllowed: r.NotAllowed, assets: r.Assets, recovery: r.RecoveryHandler, errors: r.ErrorHandler}
+	if r.fallback != nil {
+		base := r.fallback.load().scopes
+		own.inherit(base[len(base)-1])
+	}
+	var scopes []*scope
+	for _, group := range r.groups {
+		if group.NotFound == nil && group.NotAllowed == nil && group.Assets == nil && group.RecoveryHandler == nil && group.ErrorHandler == nil {
+			continue
+		}
+		s := &scope{prefix: strings.TrimSuffix(r.routePattern(group.Prefix), "/")}
+		for g := group; g != nil; g = g.parent {
+			if s.notFound == nil {
+				s.notFound = g.NotFound
+			}
+			if s.notAllowed == nil {
+				s.notAllowed = g.NotAllowed
+			}
+			if s.assets == nil {
+				s.assets = g.Assets
+			}
+			if s.recovery == nil {
+				s.recovery = g.RecoveryHandler
+			}
+			if s.errors == nil {
+				s.errors = g.ErrorHandler
+			}
+		}
+		s.inherit(own)
+		scopes = append(scopes, s)
+	}
+	sort.SliceStable(scopes, func(i, j int) bool {
+		return len(scopes[i].prefix) > len(scopes[j].prefix)
+	})
+	return append(scopes, own)
+}
+
+// inherit sets the handlers s does not have to those of parent.
+func (s *scope) inherit(parent *scope) {
+	if s.notFound == nil {
+		s.notFound = parent.notFound
+	}
+	if s.notAllowed == nil {
+		s.notAllowed = parent.notAllowed
+	}
+	if s.assets == nil {
+		s.assets = parent.assets
+	}
+	if s.recovery == nil {
+		s.recovery = parent.recovery
+	}
+	if s.errors == nil {
+		s.errors = parent.errors
+	}
+}
+
+// scope returns the handlers for a request path: those of the group with the
+// longest prefix of the path, or else the router's own.
+func (t *routeTable) scope(path string) *scope {
+	fold := !t.caseSensitive
+	for _, s := range t.scopes {
+		if n := len(s.prefix); hasPrefix(path, s.prefix, fold) && (len(path) == n || path[n] == '/') {
+			return s
+		}
+	}
+	return t.scopes[len(t.scopes)-1]
+}
+
+// load returns the published route table.
+func (r *router) load() *routeTable {
+	return r.table.Load().(*routeTable)
+}
+
+// requestPath normalizes a request path for matching. In lenient mode the
+// path is cleaned, which drops dot segments, repeated and trailing slashes.
+func (t *routeTable) requestPath(p string) string {
+	if t.pathMode == PathLenient {
+		if p == "" || p[0] != '/' {
+			p = "/" + p
+		}
+		return path.Clean(p)
+	}
+	if p == "" || p[0] != '/' {
+		return "/" + p
+	}
+	return p
+}
+
+// cleanPath cleans a request path like path.Clean but keeps its trailing slash.
+func cleanPath(p string) string {
+	cleaned := path.Clean(p)
+	if cleaned != "/" && strings.HasSuffix(p, "/") {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// lookup finds the route for a request path, appending its parameters to ps.
+func (t *routeTable) lookup(method, path string, ps *Params, fold bool) *Route {
+	root := t.trees[method]
+	if root == nil {
+		return nil
+	}
+	saved := len(*ps)
+	route := root.match(path, ps, fold)
+	if route == nil {
+		*ps = (*ps)[:saved]
+	}
+	return route
+}
+
+// route matches the request and sets the route and middleware chain to run on
+// the context. For HEAD requests served by a GET route it also returns the
+// response writer to finish.
+func (t *routeTable) route(ctx *HttpContext) *headResponseWriter {
+	requestPath := t.requestPath(ctx.Req.URL.Path)
+	method := ctx.Req.Method
+	if t.pathMode != PathLenient && cleanPath(requestPath) != requestPath {
+		// Paths with repeated slashes or dot segments match no route outside
+		// lenient mode; serveUnmatched redirects or rejects them.
+		ctx.Route = nil
+		ctx.handlers = t.unmatched
+		return nil
+	}
+
+	// Match before the hooks run so that they can read the route metadata.
+	route := t.lookup(method, requestPath, &ctx.Params, !t.caseSensitive)
+	var head *headResponseWriter
+	if route == nil && method == http.MethodHead {
+		// Serve HEAD with the GET handler, discarding the body.
+		if route = t.lookup(http.MethodGet, requestPath, &ctx.Params, !t.caseSensitive); route != nil {
+			head = &headResponseWriter{ResponseWriter: ctx.W}
+			ctx.W = head
+		}
+	}
+
+	ctx.Route = route
+	if route == nil {
+		ctx.handlers = t.unmatched
+	} else {
+		ctx.handlers = t.chains[route.id]
+	}
+	return head
+}
+
+// serveUnmatched answers a request that matched no route: it redirects to the
+// registered path or the cleaned path, answers OPTIONS or 405 if the path exists for other
+// methods, and otherwise tries the assets handler before responding 404.
+func (t *routeTable) serveUnmatched(ctx *HttpContext) {
+	w, req, method := ctx.W, ctx.Req, ctx.Req.Method
+
+	requestPath := t.requestPath(req.URL.Path)
+	cleaned := cleanPath(requestPath)
+	if t.pathMode == PathRedirect {
+		target, found := t.redirectPath(req.URL.Path, method)
+		if !found && cleaned != requestPath {
+			target, found = cleaned, true
+		}
+		if found {
+			redirect(w, req, target)
+			return
+		}
+	}
+	if t.pathMode == PathStrict && cleaned != requestPath {
+		t.scope(cleaned).notFound(ctx) // Unclean paths never match in strict mode.
+		return
+	}
+	allowed := t.allowedMethods(requestPath, method)
+	handlers := t.scope(requestPath)
+
+	// The path may exist for other methods: answer OPTIONS or 405.
+	if len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		if method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+		} else {
+			handlers.notAllowed(ctx) // Handle 405 Method Not Allowed.
+		}
+		return
+	}
+	if !handlers.assets(ctx) {
+		return
+	}
+	handlers.notFound(ctx) // Handle 404 Not Found.
+}
+
+// redirectPath returns the registered path that a non-canonical request path
+// stands for: the cleaned path, with or without a trailing slash, and with the
+// case of static segments fixed on case-sensitive routers.
+func (t *routeTable) redirectPath(requestPath, method string) (string, bool) {
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+	cleaned := path.Clean("/" + requestPath)
+	candidates := []string{cleaned}
+	if cleaned != "/" {
+		candidates = append(candidates, cleaned+"/")
+	}
+
+	folds := []bool{!t.caseSensitive}
+	if t.caseSensitive {
+		folds = append(folds, true)
+	}
+	var ps Params
+	for _, fold := range folds {
+		for _, candidate := range candidates {
+			ps = ps[:0]
+			if route := t.lookup(method, candidate, &ps, fold); route != nil {
+				if target := route.canonicalPath(ps); target != requestPath {
+					return target, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// allowedMethods returns the sorted methods, other than method, that have a route
+// for the path. OPTIONS is included since it is answered automatically,
+// and HEAD is included whenever GET is.
+func (t *routeTable) allowedMethods(path, method string) []string {
+	var allowed []string
+	var ps Params
+	hasGet := false
+	for m := range t.trees {
+		if m == method || m == http.MethodOptions || m == http.MethodHead {
+			continue
+		}
+		if t.lookup(m, path, &ps, !t.caseSensitive) != nil {
+			allowed = append(allowed, m)
+			hasGet = hasGet || m == http.MethodGet
+		}
+	}
+	if method != http.MethodHead && (hasGet || t.lookup(http.MethodHead, path, &ps, !t.caseSensitive) != nil) {
+		allowed = append(allowed, http.MethodHead) // HEAD falls back to GET.
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+	allowed = append(allowed, http.MethodOptions)
+	sort.Strings(allowed)
+	return allowed
+}
+
+// RemoveRoute removes the route registered with the method and path, as given
+// to AddRoute, and reports whether there was one. Requests already being
+// served finish with the removed route.
+func (r *router) RemoveRoute(method, path string) bool {
+	top := r.top()
+	top.mu.Lock()
+	defer top.mu.Unlock()
+
+	route := top.findRoute(method, r.routePattern(path))
+	if route == nil {
+		return false
+	}
+	root := top.Trees[method].clone()
+	top.unsetRoute(root, route)
+	top.Trees[method] = root
+	top.dropRoute(route)
+	top.publish()
+	return true
+}
+
+// ReplaceRoute registers a route like AddRoute, replacing the route registered
+// with the same method and path if there is one. Requests see either the old
+// or the new route.
+func (r *router) ReplaceRoute(method, path string, handler Handler, opts ...RouteOption) {
+	top := r.top()
+	top.mu.Lock()
+	defer top.mu.Unlock()
+
+	r.addRoute(method, path, handler, opts, true)
+	top.publish()
+}
+
+// Clone returns a staging copy of the router's routes, hooks and handlers for
+// build-then-swap updates: routes can be added to and removed from the copy,
+// or its groups, while r keeps serving its own, and Swap publishes them to r
+// at once. The copy shares the unchanged tree nodes with r.
+func (r *router) Clone() *router {
+	top := r.top()
+	top.mu.RLock()
+	defer top.mu.RUnlock()
+
+	c := NewRouter()
+	c.fallback = top.fallback
+	for method, root := range top.Trees {
+		c.Trees[method] = root
+	}
+	for name, route := range top.Names {
+		c.Names[name] = route
+	}
+	c.routes = append([]*Route(nil), top.routes...)
+	c.chains = append([][]func(ctx *HttpContext){}, top.chains...)
+	c.freeIDs = append([]int(nil), top.freeIDs...)
+	c.BeforeHooks = append([]func(ctx *HttpContext) bool{}, top.BeforeHooks...)
+	c.AfterHooks = append([]func(ctx *HttpContext){}, top.AfterHooks...)
+	c.GroupBefore = append([]func(ctx *HttpContext) bool{}, top.GroupBefore...)
+	c.GroupAfter = append([]func(ctx *HttpContext){}, top.GroupAfter...)
+	c.Middlewares = append([]func(ctx *HttpContext){}, top.Middlewares...)
+	c.GroupMiddlewares = append([]func(ctx *HttpContext){}, top.GroupMiddlewares...)
+	c.NotFound, c.NotAllowed, c.Assets = top.NotFound, top.NotAllowed, top.Assets
+	c.RecoveryHandler, c.ErrorHandler = top.RecoveryHandler, top.ErrorHandler
+	c.CaseSensitive, c.PathMode = top.CaseSensitive, top.PathMode
+	c.Mode, c.Envelope, c.ErrorStatus = top.Mode, top.Envelope, top.ErrorStatus
+	c.hosts = top.hosts
+	c.publish()
+	return c
+}
+
+// Swap atomically replaces the routes of r with the routes of staged, usually
+// a copy made with Clone. Requests already being served finish with the old
+// routes, and new requests see all the new ones. The hooks, middleware and
+// handlers of r are kept, and the route chains are rebuilt with them. Groups
+// created on staged move to r with their hooks and handlers; staged must not
+// be used afterwards.
+func (r *router) Swap(staged *router) {
+	staged = staged.top()
+	staged.mu.RLock()
+	trees := make(map[string]*TrieNode, len(staged.Trees))
+	for method, root := range staged.Trees {
+		trees[method] = root
+	}
+	names := make(map[string]*Route, len(staged.Names))
+	for name, route := range staged.Names {
+		names[name] = route
+	}
+	routes := append([]*Route(nil), staged.routes...)
+	groups := append([]*router(nil), staged.groups...)
+	freeIDs := append([]int(nil), staged.freeIDs...)
+	chains := len(staged.chains)
+	staged.mu.RUnlock()
+
+	top := r.top()
+	top.mu.Lock()
+	defer top.mu.Unlock()
+
+	for _, group := range groups {
+		if group.root == top {
+			continue // Already a group of r.
+		}
+		group.root = top
+		if group.parent == staged {
+			group.parent = top
+		}
+		top.groups = append(top.groups, group)
+	}
+	top.Trees, top.Names, top.routes, top.freeIDs = trees, names, routes, freeIDs
+	top.chains = make([][]func(ctx *HttpContext), chains)
+	top.rebuildChains()
+}
+
+// findRoute returns the route registered with the method and normalized path, or nil.
+func (r *router) findRoute(method, pattern string) *Route {
+	for _, route := range r.routes {
+		if route.Method == method && route.Path == pattern {
+			return route
+		}
+	}
+	return nil
+}
+
+// unsetRoute removes route from the leaves it ends at in the tree below root,
+// which must be a private copy. The emptied nodes are kept; they match nothing.
+func (r *router) unsetRoute(root *TrieNode, route *Route) {
+	segments, optional, _ := optionalSegments(strings.Split(route.Path[1:], "/"))
+	root.insertVariants(segments, optional, r.CaseSensitive, func(leaf *TrieNode) {
+		if leaf.Route == route {
+			leaf.Route = nil
+		}
+	})
+}
+
+// dropRoute removes route from the route list and the named routes, and frees
+// its id for the next route. A name shared with a route of another method
+// moves to that route.
+func (r *router) dropRoute(route *Route) {
+	routes := make([]*Route, 0, len(r.routes))
+	for _, other := range r.routes {
+		if other != route {
+			routes = append(routes, other)
+		}
+	}
+	r.routes = routes
+	r.freeIDs = append(r.freeIDs, route.id)
+
+	if route.Name == "" || r.Names[route.Name] != route {
+		return
+	}
+	delete(r.Names, route.Name)
+	for _, other := range r.routes {
+		if other.Name == route.Name {
+			r.Names[route.Name] = other
+			break
+		}
+	}
+}
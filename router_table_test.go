@@ -0,0 +1,156 @@
+package invoke
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSwapKeepsStagedGroups(t *testing.T) {
+	r := NewRouter()
+	r.GET("/v1/x", func(ctx *HttpContext) {})
+
+	staged := r.Clone()
+	v2 := staged.Group("/v2")
+	v2.SetNotFoundHandler(func(ctx *HttpContext) { ctx.AbortWithStatus(419) })
+	v2.GET("/x", func(ctx *HttpContext) { ctx.WriteString("v2") })
+	r.Swap(staged)
+
+	if _, body := serveBody(r, "GET", "/v2/x"); body != "v2" {
+		t.Errorf("GET /v2/x = %q", body)
+	}
+	if code, _ := serveBody(r, "GET", "/v2/nope"); code != 419 {
+		t.Errorf("GET /v2/nope = %d, want the staged group's 419", code)
+	}
+	v2.GET("/y", func(ctx *HttpContext) {})
+	if code, _ := serveBody(r, "GET", "/v2/y"); code != 200 {
+		t.Errorf("route added to the swapped group after Swap: %d", code)
+	}
+}
+
+func TestRemovedRouteIDsAreReused(t *testing.T) {
+	r := NewRouter()
+	r.GET("/base", func(ctx *HttpContext) {})
+	for i := 0; i < 100; i++ {
+		r.GET("/plugin", func(ctx *HttpContext) { ctx.WriteString(fmt.Sprint(i)) })
+		if _, body := serveBody(r, "GET", "/plugin"); body != fmt.Sprint(i) {
+			t.Fatalf("GET /plugin = %q, want %d", body, i)
+		}
+		r.RemoveRoute("GET", "/plugin")
+		r.ReplaceRoute("GET", "/base", func(ctx *HttpContext) {})
+	}
+	if n := len(r.chains); n > 3 {
+		t.Errorf("%d route chains after enabling and disabling one route, want at most 3", n)
+	}
+}
+
+func TestPathModes(t *testing.T) {
+	tests := []struct {
+		mode      PathMode
+		sensitive bool
+		method    string
+		path      string
+		code      int
+		want      string // Body for 200, Location for redirects.
+	}{
+		{PathLenient, false, "GET", "/users", 200, "users"},
+		{PathLenient, false, "GET", "/users/", 200, "users"},
+		{PathLenient, false, "GET", "//users", 200, "users"},
+		{PathLenient, false, "GET", "/files/x/../y", 200, "file y"},
+		{PathLenient, false, "GET", "/files/./y", 200, "file y"},
+		{PathLenient, false, "GET", "/files/..", 404, ""},
+		{PathLenient, false, "GET", "/docs", 200, "docs"},
+		{PathLenient, false, "GET", "/USERS", 200, "users"},
+
+		{PathStrict, false, "GET", "/users", 200, "users"},
+		{PathStrict, false, "GET", "/users/", 404, ""},
+		{PathStrict, false, "GET", "//users", 404, ""},
+		{PathStrict, false, "GET", "/files/x/../y", 404, ""},
+		{PathStrict, false, "GET", "/files/..", 404, ""},
+		{PathStrict, false, "POST", "/files/..", 404, ""},
+		{PathStrict, false, "GET", "/docs/", 200, "docs"},
+		{PathStrict, false, "GET", "/docs", 404, ""},
+		{PathStrict, true, "GET", "/USERS", 404, ""},
+
+		{PathRedirect, false, "GET", "/users", 200, "users"},
+		{PathRedirect, false, "GET", "/users/", 301, "/users"},
+		{PathRedirect, false, "GET", "/users/?page=2", 301, "/users?page=2"},
+		{PathRedirect, false, "HEAD", "/users/", 301, "/users"},
+		{PathRedirect, false, "POST", "/users/", 308, "/users"},
+		{PathRedirect, false, "GET", "//users", 301, "/users"},
+		{PathRedirect, false, "GET", "/docs", 301, "/docs/"},
+		{PathRedirect, false, "GET", "/files/x/../y", 301, "/files/y"},
+		{PathRedirect, false, "GET", "/files/..", 301, "/"},
+		{PathRedirect, false, "GET", "/nope/./x/", 301, "/nope/x/"},
+		{PathRedirect, false, "GET", "/nope", 404, ""},
+		{PathRedirect, true, "GET", "/USERS", 301, "/users"},
+		{PathRedirect, true, "GET", "/files/X", 200, "file X"},
+		{PathRedirect, true, "POST", "/Users", 308, "/users"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d/%v/%s %s", tt.mode, tt.sensitive, tt.method, tt.path), func(t *testing.T) {
+			r := NewRouter()
+			r.SetPathMode(tt.mode)
+			r.SetCaseSensitive(tt.sensitive)
+			r.GET("/users", func(ctx *HttpContext) { ctx.WriteString("users") })
+			r.POST("/users", func(ctx *HttpContext) { ctx.WriteString("users") })
+			r.GET("/docs/", func(ctx *HttpContext) { ctx.WriteString("docs") })
+			r.GET("/files/:name", func(ctx *HttpContext) { ctx.WriteString("file " + ctx.Param("name")) })
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			got := w.Body.String()
+			if w.Code >= 300 && w.Code < 400 {
+				got = w.Header().Get("Location")
+			} else if w.Code != 200 {
+				got = ""
+			}
+			if w.Code != tt.code || got != tt.want {
+				t.Errorf("%s %s = %d %q, want %d %q", tt.method, tt.path, w.Code, got, tt.code, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupScopes(t *testing.T) {
+	r := NewRouter()
+	r.SetAssetsHandler(func(ctx *HttpContext) bool { return true })
+	api := r.Group("/api")
+	api.SetNotFoundHandler(func(ctx *HttpContext) { ctx.WriteString("api 404") })
+	api.SetNotAllowedHandler(func(ctx *HttpContext) { ctx.WriteString("api 405") })
+	api.SetRecoveryHandler(func(ctx *HttpContext, err interface{}) { ctx.WriteString(fmt.Sprint("api recovered ", err)) })
+	api.SetAssetsHandler(func(ctx *HttpContext) bool {
+		if strings.HasSuffix(ctx.Req.URL.Path, ".png") {
+			ctx.WriteString("api asset")
+			return false
+		}
+		return true
+	})
+	v1 := api.Group("/v1")
+	v1.GET("/users", func(ctx *HttpContext) {})
+	v1.GET("/panic", func(ctx *HttpContext) { panic("boom") })
+	admin := v1.Group("/admin")
+	admin.SetNotFoundHandler(func(ctx *HttpContext) { ctx.WriteString("admin 404") })
+	r.GET("/apix/panic", func(ctx *HttpContext) { panic("boom") })
+
+	for _, tt := range []struct{ method, path, want string }{
+		{"GET", "/api/nope", "api 404"},
+		{"GET", "/api/v1/nope", "api 404"},
+		{"GET", "/api/v1/admin/nope", "admin 404"},
+		{"POST", "/api/v1/users", "api 405"},
+		{"GET", "/api/v1/panic", "api recovered boom"},
+		{"GET", "/api/logo.png", "api asset"},
+		{"GET", "/api/v1/admin/logo.png", "api asset"},
+		{"GET", "/apix/logo.png", "404 - Not Found\n"},
+		{"GET", "/apix/nope", "404 - Not Found\n"},
+		{"GET", "/API/nope", "api 404"},
+	} {
+		if _, body := serveBody(r, tt.method, tt.path); body != tt.want {
+			t.Errorf("%s %s = %q, want %q", tt.method, tt.path, body, tt.want)
+		}
+	}
+	if code, body := serveBody(r, "GET", "/apix/panic"); code != 500 || strings.Contains(body, "api recovered") {
+		t.Errorf("GET /apix/panic = %d %q, want the router's recovery", code, body)
+	}
+}
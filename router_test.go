@@ -0,0 +1,187 @@
+package invoke
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// serveBody serves a request to the router and returns the status code and body.
+func serveBody(r *router, method, target string) (int, string) {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	return w.Code, w.Body.String()
+}
+
+func TestUseGroupOnRouter(t *testing.T) {
+	r := NewRouter()
+	r.UseGroup(func(ctx *HttpContext) { ctx.W.Write([]byte("mw ")); ctx.Next() })
+	r.RegisterGroupBeforeHook(func(ctx *HttpContext) bool { ctx.W.Write([]byte("hook ")); return true })
+	r.GET("/x", func(ctx *HttpContext) { ctx.W.Write([]byte("x")) })
+	g := r.Group("/g")
+	g.GET("/y", func(ctx *HttpContext) { ctx.W.Write([]byte("y")) })
+
+	if _, body := serveBody(r, "GET", "/x"); body != "mw hook x" {
+		t.Errorf("GET /x = %q", body)
+	}
+	if _, body := serveBody(r, "GET", "/g/y"); body != "mw hook y" {
+		t.Errorf("GET /g/y = %q, want the router's group middleware and hook once", body)
+	}
+}
+
+func TestGroupSetsRouterMatching(t *testing.T) {
+	r := NewRouter()
+	g := r.Group("/api")
+	g.SetCaseSensitive(true)
+	g.SetPathMode(PathStrict)
+	g.GET("/Users/", func(ctx *HttpContext) {})
+
+	if !r.CaseSensitive || r.PathMode != PathStrict {
+		t.Fatal("group setters did not change the router")
+	}
+	if code, _ := serveBody(r, "GET", "/api/Users/"); code != 200 {
+		t.Errorf("GET /api/Users/ = %d", code)
+	}
+	for _, path := range []string{"/api/users/", "/api/Users"} {
+		if code, _ := serveBody(r, "GET", path); code != 404 {
+			t.Errorf("GET %s = %d, want 404", path, code)
+		}
+	}
+}
+
+func TestRequestCarriesParams(t *testing.T) {
+	r := NewRouter()
+	r.GET("/user/:id", func(ctx *HttpContext) {
+		if GetParams(ctx.Req) != nil {
+			t.Error("ServeHTTP stored params on ctx.Req")
+		}
+		ctx.WriteString(GetParams(ctx.Request())["id"])
+	})
+	if _, body := serveBody(r, "GET", "/user/42"); body != "42" {
+		t.Errorf("GetParams(ctx.Request()) id = %q", body)
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	r := NewRouter()
+	r.GET("/users/:id", func(ctx *HttpContext) {})
+	r.PUT("/users/:id", func(ctx *HttpContext) {})
+	r.DELETE("/users/:id", func(ctx *HttpContext) {})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/users/42", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /users/42 = %d, want 405", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "DELETE, GET, HEAD, OPTIONS, PUT" {
+		t.Errorf("Allow = %q", allow)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/orders/42", nil))
+	if w.Code != http.StatusNotFound || w.Header().Get("Allow") != "" {
+		t.Errorf("POST /orders/42 = %d with Allow %q, want 404", w.Code, w.Header().Get("Allow"))
+	}
+}
+
+func TestAutomaticOptions(t *testing.T) {
+	r := NewRouter()
+	r.GET("/users/:id", func(ctx *HttpContext) {})
+	r.POST("/users/:id", func(ctx *HttpContext) {})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("OPTIONS", "/users/42", nil))
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Errorf("OPTIONS /users/42 = %d %q, want 204", w.Code, w.Body.String())
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET, HEAD, OPTIONS, POST" {
+		t.Errorf("Allow = %q", allow)
+	}
+}
+
+func TestExplicitOptionsRoute(t *testing.T) {
+	r := NewRouter()
+	r.GET("/users/:id", func(ctx *HttpContext) {})
+	r.OPTIONS("/users/:id", func(ctx *HttpContext) {
+		ctx.W.Header().Set("Allow", "GET")
+		ctx.WriteString("custom")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("OPTIONS", "/users/42", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "custom" || w.Header().Get("Allow") != "GET" {
+		t.Errorf("OPTIONS /users/42 = %d %q with Allow %q, want the registered handler", w.Code, w.Body.String(), w.Header().Get("Allow"))
+	}
+}
+
+func TestHeadFallsBackToGet(t *testing.T) {
+	r := NewRouter()
+	r.GET("/report", func(ctx *HttpContext) {
+		ctx.W.Header().Set("X-Report", "daily")
+		ctx.W.Header().Set("Content-Type", "text/plain")
+		ctx.W.WriteHeader(http.StatusAccepted)
+		ctx.WriteString("report body")
+	})
+	r.GET("/sized", func(ctx *HttpContext) {
+		ctx.W.Header().Set("Content-Length", "100")
+		ctx.WriteString("short")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("HEAD", "/report", nil))
+	if w.Code != http.StatusAccepted || w.Body.Len() != 0 {
+		t.Errorf("HEAD /report = %d %q, want 202 without a body", w.Code, w.Body.String())
+	}
+	if w.Header().Get("X-Report") != "daily" || w.Header().Get("Content-Type") != "text/plain" {
+		t.Errorf("HEAD /report headers = %v", w.Header())
+	}
+	if w.Header().Get("Content-Length") != "11" {
+		t.Errorf("HEAD /report Content-Length = %q, want 11", w.Header().Get("Content-Length"))
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("HEAD", "/sized", nil))
+	if w.Header().Get("Content-Length") != "100" || w.Body.Len() != 0 {
+		t.Errorf("HEAD /sized Content-Length = %q, want the handler's 100", w.Header().Get("Content-Length"))
+	}
+}
+
+func TestExplicitHeadRoute(t *testing.T) {
+	r := NewRouter()
+	r.GET("/status", func(ctx *HttpContext) { ctx.W.Header().Set("X-From", "get") })
+	r.HEAD("/status", func(ctx *HttpContext) { ctx.W.Header().Set("X-From", "head") })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("HEAD", "/status", nil))
+	if from := w.Header().Get("X-From"); from != "head" {
+		t.Errorf("HEAD /status served by %q, want the HEAD route", from)
+	}
+}
+
+func TestMultiMethodRegistration(t *testing.T) {
+	r := NewRouter()
+	r.Handle([]string{"GET", "POST"}, "/form", func(ctx *HttpContext) { ctx.WriteString("form " + ctx.Req.Method) })
+	r.HandlePaths([]string{"PUT"}, []string{"/a", "/b"}, func(ctx *HttpContext) { ctx.WriteString(ctx.Req.URL.Path) })
+	r.Any("/any", func(ctx *HttpContext) { ctx.WriteString("any " + ctx.Req.Method) })
+
+	for _, tt := range []struct{ method, path, want string }{
+		{"GET", "/form", "form GET"},
+		{"POST", "/form", "form POST"},
+		{"PUT", "/a", "/a"},
+		{"PUT", "/b", "/b"},
+		{"GET", "/any", "any GET"},
+		{"PATCH", "/any", "any PATCH"},
+		{"DELETE", "/any", "any DELETE"},
+		{"OPTIONS", "/any", "any OPTIONS"},
+	} {
+		if code, body := serveBody(r, tt.method, tt.path); code != 200 || body != tt.want {
+			t.Errorf("%s %s = %d %q, want %q", tt.method, tt.path, code, body, tt.want)
+		}
+	}
+	if code, _ := serveBody(r, "DELETE", "/form"); code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE /form = %d, want 405", code)
+	}
+	if code, _ := serveBody(r, "GET", "/a"); code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /a = %d, want 405", code)
+	}
+}
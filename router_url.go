@@ -0,0 +1,71 @@
+package invoke
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// URL builds the path of the named route, filling its parameter segments from
+// params. Values of regex segments must match their pattern, and every
+// parameter of the route must be given; catch-all segments may be empty, and
+// optional segments are left out from the first one without a value.
+func (r *router) URL(name string, params map[string]string) (string, error) {
+	top := r.top()
+	top.mu.RLock()
+	defer top.mu.RUnlock()
+
+	route, ok := top.Names[name]
+	if !ok {
+		return "", fmt.Errorf("route %q is not registered", name)
+	}
+
+	segments := make([]string, len(route.parts))
+	for i, part := range route.parts {
+		switch part.NodeType {
+		case Static:
+			segments[i] = part.Text
+			continue
+		case CatchAll:
+			value := strings.TrimPrefix(params[part.Text], "/")
+			pieces := strings.Split(value, "/")
+			for j, piece := range pieces {
+				pieces[j] = url.PathEscape(piece)
+			}
+			segments[i] = strings.Join(pieces, "/")
+			continue
+		}
+
+		value, ok := params[part.Text]
+		if part.Optional && value == "" {
+			segments = segments[:i] // Leave out the missing optional segments.
+			break
+		}
+		if !ok || value == "" {
+			return "", fmt.Errorf("route %q: missing parameter %q", name, part.Text)
+		}
+		if part.NodeType == Regex && !part.matchSegment(value) {
+			return "", fmt.Errorf("route %q: parameter %q value %q does not match %q", name, part.Text, value, part.Pattern)
+		}
+		segments[i] = url.PathEscape(value)
+	}
+	return "/" + strings.Join(segments, "/"), nil
+}
+
+// matchSegment reports whether value satisfies the regex or typed constraint of a Regex part.
+func (part routePart) matchSegment(value string) bool {
+	if part.Check != nil {
+		_, ok := part.Check(value)
+		return ok
+	}
+	return part.Regexp.MatchString(value)
+}
+
+// MustURL is like URL but panics if the URL cannot be built.
+func (r *router) MustURL(name string, params map[string]string) string {
+	u, err := r.URL(name, params)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
@@ -0,0 +1,42 @@
+package invoke
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestURL(t *testing.T) {
+	r := NewRouter()
+	api := r.Group("/api/v1")
+	api.GET("/users/:id/posts/{post:[0-9]+}", func(ctx *HttpContext) {}, WithName("post"))
+	api.GET("/files/*path", func(ctx *HttpContext) {}, WithName("file"))
+
+	tests := []struct {
+		name   string
+		params map[string]string
+		want   string
+		err    string
+	}{
+		{"post", map[string]string{"id": "ann lee", "post": "7"}, "/api/v1/users/ann%20lee/posts/7", ""},
+		{"post", map[string]string{"post": "7"}, "", `missing parameter "id"`},
+		{"post", map[string]string{"id": "", "post": "7"}, "", `missing parameter "id"`},
+		{"post", map[string]string{"id": "ann", "post": "x7"}, "", `parameter "post" value "x7" does not match`},
+		{"file", map[string]string{"path": "css/site.css"}, "/api/v1/files/css/site.css", ""},
+		{"nope", nil, "", `route "nope" is not registered`},
+	}
+	for _, tt := range tests {
+		got, err := r.URL(tt.name, tt.params)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("URL(%q, %v) error = %v, want %q", tt.name, tt.params, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("URL(%q, %v) = %q, %v, want %q", tt.name, tt.params, got, err, tt.want)
+		}
+	}
+	if got := api.MustURL("file", map[string]string{"path": "a b"}); got != "/api/v1/files/a%20b" {
+		t.Errorf("MustURL from the group = %q", got)
+	}
+}
@@ -0,0 +1,383 @@
+package invoke
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// NodeType represents the type of trie node.
+type NodeType int
+
+const (
+	Static   NodeType = iota // Static node type for regular string nodes.
+	Param                    // Param node type for parameter nodes, e.g., /user/:id.
+	Regex                    // Regex node type for regex and typed nodes, e.g., /product/{id:[0-9]+} or /product/{id:int}.
+	CatchAll                 // CatchAll node type for the rest of the path, e.g., /static/*filepath.
+)
+
+// TrieNode represents a node in the radix tree of one HTTP method.
+// Static nodes hold a compressed run of path bytes and index their static
+// children by first byte; parameter nodes stand for one whole path segment.
+type TrieNode struct {
+	Path     string         `json:"path"`                // Static path bytes, or the segment pattern of a parameter node.
+	NodeType NodeType       `json:"node_type"`           // Type of the node (Static, Param, Regex, CatchAll).
+	Param    string         `json:"param,omitempty"`     // Parameter name captured by Param, Regex and CatchAll nodes.
+	Regexp   *regexp.Regexp `json:"-"`                   // Compiled pattern for Regex nodes.
+	Check    Constraint     `json:"-"`                   // Typed constraint for Regex nodes such as {id:int}, used instead of Regexp.
+	Indices  string         `json:"indices,omitempty"`   // First byte of each static child, lowercased on case-insensitive routers.
+	Children []*TrieNode    `json:"children,omitempty"`  // Static children, in the order of Indices.
+	Regexes  []*TrieNode    `json:"regexes,omitempty"`   // Regex children, in registration order.
+	Params   []*TrieNode    `json:"params,omitempty"`    // Param children, in registration order.
+	CatchAll *TrieNode      `json:"catch_all,omitempty"` // Catch-all child.
+	Route    *Route         `json:"route,omitempty"`     // Route ending at this node.
+}
+
+// routePart is one path segment of a route pattern.
+type routePart struct {
+	NodeType NodeType       // Type of the segment.
+	Text     string         // Static text, or the parameter name.
+	Pattern  string         // Pattern of Regex segments, as written in the route.
+	Regexp   *regexp.Regexp // Compiled pattern for Regex segments.
+	Check    Constraint     // Typed constraint for Regex segments.
+	Optional bool           // Whether the segment may be left out, e.g., /report/:year/:month?.
+}
+
+// insertRoute adds the path segments of a route below the root node and
+// returns the node the route ends at with the parsed segments. Unless the
+// router is case-sensitive, static text is compared ignoring ASCII case so
+// that case variants of a path share their nodes. The node must be a private
+// copy: existing nodes on the path are copied before they are changed, so
+// trees published to ServeHTTP are never modified.
+func (n *TrieNode) insertRoute(segments []string, caseSensitive bool) (*TrieNode, []routePart, error) {
+	fold := !caseSensitive
+	parts := make([]routePart, 0, len(segments))
+	static := "/"
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		nodeType, pattern, paramName := getNodeTypeAndPattern(segment) // Determine node type and pattern.
+		if nodeType == Static {
+			parts = append(parts, routePart{NodeType: Static, Text: segment})
+			static += segment
+			if !last {
+				static += "/"
+			}
+			continue
+		}
+
+		if nodeType == CatchAll && (!last || pattern == "") {
+			return nil, nil, fmt.Errorf("catch-all segment '%s' must be named and be the last segment", segment)
+		}
+		n = n.insertStatic(static, fold)
+		child, err := n.paramChild(nodeType, segment, pattern, paramName, caseSensitive)
+		if err != nil {
+			return nil, nil, err
+		}
+		part := routePart{NodeType: nodeType, Text: child.Param, Regexp: child.Regexp, Check: child.Check}
+		if nodeType == Regex {
+			part.Pattern = pattern
+		}
+		parts = append(parts, part)
+		n = child
+		static = ""
+		if !last {
+			static = "/"
+		}
+	}
+	return n.insertStatic(static, fold), parts, nil
+}
+
+// optionalSegments strips the "?" suffix of optional segments and returns the
+// index of the first one, or len(segments) if there is none. Only parameter
+// segments can be optional, and only at the end of the route.
+func optionalSegments(segments []string) ([]string, int, error) {
+	stripped := make([]string, len(segments))
+	first := len(segments)
+	for i, segment := range segments {
+		if !strings.HasSuffix(segment, "?") {
+			if first < len(segments) {
+				return nil, 0, fmt.Errorf("segment '%s' follows an optional segment", segment)
+			}
+			stripped[i] = segment
+			continue
+		}
+		stripped[i] = segment[:len(segment)-1]
+		if nodeType, _, _ := getNodeTypeAndPattern(stripped[i]); nodeType != Param && nodeType != Regex {
+			return nil, 0, fmt.Errorf("optional segment '%s' is not a parameter", segment)
+		}
+		if first == len(segments) {
+			first = i
+		}
+	}
+	return stripped, first, nil
+}
+
+// insertVariants inserts a route once for each number of its optional
+// segments present, from index optional on, and calls set with the node each
+// variant ends at. It returns the parsed segments of the longest variant.
+// Nodes on the path may be copied by later insertions, so set must do its work
+// on the node right away rather than keep it.
+func (n *TrieNode) insertVariants(segments []string, optional int, caseSensitive bool, set func(leaf *TrieNode)) ([]routePart, error) {
+	var parts []routePart
+	for i := optional; i <= len(segments); i++ {
+		leaf, leafParts, err := n.insertRoute(segments[:i], caseSensitive)
+		if err != nil {
+			return nil, err
+		}
+		set(leaf)
+		parts = leafParts
+	}
+	for i := optional; i < len(parts); i++ {
+		parts[i].Optional = true
+	}
+	return parts, nil
+}
+
+// insertStatic adds the static text s below the node, splitting nodes where
+// s diverges from them, and returns the node s ends at.
+func (n *TrieNode) insertStatic(s string, fold bool) *TrieNode {
+	for s != "" {
+		i := n.staticChild(s[0], fold)
+		if i < 0 {
+			child := &TrieNode{Path: s, NodeType: Static}
+			n.Indices += string(indexByte(s[0], fold))
+			n.Children = append(n.Children, child)
+			return child
+		}
+
+		child := n.Children[i].clone()
+		n.Children[i] = child
+		l := commonPrefix(child.Path, s, fold)
+		if l < len(child.Path) {
+			child.split(l, fold)
+		}
+		s = s[l:]
+		n = child
+	}
+	return n
+}
+
+// clone returns a copy of the node with its own child lists, which can be
+// changed without affecting the node.
+func (n *TrieNode) clone() *TrieNode {
+	c := *n
+	c.Children = append([]*TrieNode(nil), n.Children...)
+	c.Regexes = append([]*TrieNode(nil), n.Regexes...)
+	c.Params = append([]*TrieNode(nil), n.Params...)
+	return &c
+}
+
+// staticChild returns the index of the static child starting with b, or -1.
+func (n *TrieNode) staticChild(b byte, fold bool) int {
+	b = indexByte(b, fold)
+	for i := 0; i < len(n.Indices); i++ {
+		if n.Indices[i] == b {
+			return i
+		}
+	}
+	return -1
+}
+
+// split cuts the node's path after l bytes, moving the rest of the path and
+// all children and the route of the node to a new static child.
+func (n *TrieNode) split(l int, fold bool) {
+	rest := *n
+	rest.Path = n.Path[l:]
+	*n = TrieNode{
+		Path:     n.Path[:l],
+		NodeType: Static,
+		Indices:  string(indexByte(rest.Path[0], fold)),
+		Children: []*TrieNode{&rest},
+	}
+}
+
+// paramChild returns the child for a parameter segment, creating it if needed.
+func (n *TrieNode) paramChild(nodeType NodeType, segment, pattern, paramName string, caseSensitive bool) (*TrieNode, error) {
+	switch nodeType {
+	case Param:
+		for i, child := range n.Params {
+			if child.Param == pattern {
+				n.Params[i] = child.clone()
+				return n.Params[i], nil
+			}
+		}
+		child := &TrieNode{Path: segment, NodeType: Param, Param: pattern}
+		n.Params = append(n.Params, child)
+		return child, nil
+	case Regex:
+		for i, child := range n.Regexes {
+			if child.Path == segment {
+				n.Regexes[i] = child.clone()
+				return n.Regexes[i], nil
+			}
+		}
+		child := &TrieNode{Path: segment, NodeType: Regex, Param: paramName}
+		check, ok, err := parseConstraint(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid constraint segment '%s': %v", segment, err)
+		}
+		if ok {
+			child.Check = check
+		} else if child.Regexp, err = compileSegmentRegex(pattern, caseSensitive); err != nil {
+			return nil, fmt.Errorf("invalid regex segment '%s': %v", segment, err)
+		}
+		n.Regexes = append(n.Regexes, child)
+		return child, nil
+	default:
+		if n.CatchAll == nil {
+			n.CatchAll = &TrieNode{Path: segment, NodeType: CatchAll, Param: pattern}
+		} else if n.CatchAll.Param != pattern {
+			return nil, fmt.Errorf("catch-all segment '%s' conflicts with '%s'", segment, n.CatchAll.Path)
+		} else {
+			n.CatchAll = n.CatchAll.clone()
+		}
+		return n.CatchAll, nil
+	}
+}
+
+// match finds the route for the rest of the request path below the node and
+// appends the captured parameters to ps. Children are tried by priority
+// (static, regex, param, catch-all), so the first complete match wins and a
+// dead-end branch falls back to its siblings. Static text ignores ASCII case
+// if fold is set.
+func (n *TrieNode) match(path string, ps *Params, fold bool) *Route {
+	if path == "" {
+		if n.Route != nil {
+			return n.Route
+		}
+		// A catch-all also matches an empty remainder, e.g. /static/ for /static/*filepath.
+		if c := n.CatchAll; c != nil && c.Route != nil {
+			*ps = append(*ps, PathParam{Key: c.Param, Value: ""})
+			return c.Route
+		}
+		return nil
+	}
+
+	b := indexByte(path[0], fold)
+	for i := 0; i < len(n.Indices); i++ {
+		if indexByte(n.Indices[i], fold) != b {
+			continue
+		}
+		child := n.Children[i]
+		label := child.Path
+		if len(path) >= len(label) && hasPrefix(path, label, fold) {
+			if route := child.match(path[len(label):], ps, fold); route != nil {
+				return route
+			}
+		} else if len(label) == len(path)+1 && label[len(path)] == '/' && hasPrefix(label, path, fold) {
+			// The path stops right before a slash, e.g. /static for /static/*filepath.
+			if c := child.CatchAll; c != nil && c.Route != nil {
+				*ps = append(*ps, PathParam{Key: c.Param, Value: ""})
+				return c.Route
+			}
+		}
+	}
+
+	end := strings.IndexByte(path, '/')
+	if end < 0 {
+		end = len(path)
+	}
+	segment := path[:end]
+	saved := len(*ps)
+	for _, child := range n.Regexes {
+		value, ok := child.matchSegment(segment)
+		if !ok {
+			continue
+		}
+		*ps = append(*ps, PathParam{Key: child.Param, Value: segment, Typed: value})
+		if route := child.match(path[end:], ps, fold); route != nil {
+			return route
+		}
+		*ps = (*ps)[:saved] // Dead end: undo the capture before trying the next sibling.
+	}
+	if segment != "" {
+		for _, child := range n.Params {
+			*ps = append(*ps, PathParam{Key: child.Param, Value: segment})
+			if route := child.match(path[end:], ps, fold); route != nil {
+				return route
+			}
+			*ps = (*ps)[:saved]
+		}
+	}
+	if c := n.CatchAll; c != nil && c.Route != nil {
+		*ps = append(*ps, PathParam{Key: c.Param, Value: path}) // Capture the rest of the path.
+		return c.Route
+	}
+	return nil
+}
+
+// matchSegment checks a path segment against the regex or typed constraint of
+// a Regex node and returns the converted value of typed segments.
+func (n *TrieNode) matchSegment(segment string) (interface{}, bool) {
+	if n.Check != nil {
+		return n.Check(segment)
+	}
+	return nil, n.Regexp.MatchString(segment)
+}
+
+// indexByte returns the byte used to index a static child, lowercased if fold is set.
+func indexByte(b byte, fold bool) byte {
+	if fold && 'A' <= b && b <= 'Z' {
+		return b + 'a' - 'A'
+	}
+	return b
+}
+
+// hasPrefix reports whether s starts with prefix, ignoring ASCII case if fold is set.
+func hasPrefix(s, prefix string, fold bool) bool {
+	if len(s) < len(prefix) {
+		return false
+	}
+	if !fold {
+		return s[:len(prefix)] == prefix
+	}
+	for i := 0; i < len(prefix); i++ {
+		if indexByte(s[i], true) != indexByte(prefix[i], true) {
+			return false
+		}
+	}
+	return true
+}
+
+// commonPrefix returns the length of the common prefix of a and b, ignoring ASCII case if fold is set.
+func commonPrefix(a, b string, fold bool) int {
+	i := 0
+	for i < len(a) && i < len(b) && indexByte(a[i], fold) == indexByte(b[i], fold) {
+		i++
+	}
+	return i
+}
+
+// getNodeTypeAndPattern determines the node type and pattern.
+func getNodeTypeAndPattern(part string) (NodeType, string, string) {
+	if strings.HasPrefix(part, ":") {
+		return Param, part[1:], ""
+	}
+	if strings.HasPrefix(part, "*") {
+		return CatchAll, part[1:], ""
+	}
+	if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+		// Extract the parameter name and regex pattern
+		content := part[1 : len(part)-1]
+		patternParts := strings.SplitN(content, ":", 2)
+		if len(patternParts) < 2 {
+			return Static, part, ""
+		}
+		return Regex, patternParts[1], patternParts[0]
+	}
+	return Static, part, ""
+}
+
+// compileSegmentRegex compiles the pattern of a {name:pattern} segment.
+// The pattern is anchored so that it must match the whole path segment, and
+// ignores case unless the router is case-sensitive.
+func compileSegmentRegex(pattern string, caseSensitive bool) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("empty pattern")
+	}
+	flags := "(?i)"
+	if caseSensitive {
+		flags = ""
+	}
+	return regexp.Compile(flags + "^(?:" + pattern + ")$")
+}
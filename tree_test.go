@@ -0,0 +1,135 @@
+package invoke
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestInvalidRegexPanics(t *testing.T) {
+	defer func() {
+		err := recover()
+		if err == nil {
+			t.Fatal("registering {id:[} did not panic")
+		}
+		msg := fmt.Sprint(err)
+		if !strings.Contains(msg, "/user/{id:[}") || !strings.Contains(msg, "invalid regex segment '{id:[}'") {
+			t.Fatalf("unclear panic message: %s", msg)
+		}
+	}()
+	NewRouter().GET("/user/{id:[}", func(ctx *HttpContext) {})
+}
+
+func TestMatchPriority(t *testing.T) {
+	tests := []struct {
+		name   string
+		routes []string
+		path   string
+		want   string // Pattern of the matched route, "" for no match.
+		params string
+	}{
+		{"static after param", []string{"/user/:id", "/user/me"}, "/user/me", "/user/me", ""},
+		{"param after static", []string{"/user/me", "/user/:id"}, "/user/me", "/user/me", ""},
+		{"param beside static", []string{"/user/:id", "/user/me"}, "/user/42", "/user/:id", "id=42"},
+		{"regex over param", []string{"/item/:name", "/item/{id:[0-9]+}"}, "/item/12", "/item/{id:[0-9]+}", "id=12"},
+		{"param when regex fails", []string{"/item/:name", "/item/{id:[0-9]+}"}, "/item/abc", "/item/:name", "name=abc"},
+		{"static dead end", []string{"/a/:x/b", "/a/c/d"}, "/a/c/b", "/a/:x/b", "x=c"},
+		{"static beside dead end", []string{"/a/:x/b", "/a/c/d"}, "/a/c/d", "/a/c/d", ""},
+		{"catch-all fallback", []string{"/files/:name/info", "/files/*path"}, "/files/x/y", "/files/*path", "path=x/y"},
+		{"param before catch-all", []string{"/files/:name/info", "/files/*path"}, "/files/x/info", "/files/:name/info", "name=x"},
+		{"regex capture undone", []string{"/p/{n:[0-9]+}/x", "/p/:name/y"}, "/p/1/y", "/p/:name/y", "name=1"},
+		{"param captures undone", []string{"/q/:a/:b/z", "/q/:c/:d/w"}, "/q/1/2/w", "/q/:c/:d/w", "c=1,d=2"},
+		{"captures undone for catch-all", []string{"/q/:a/:b/z", "/q/*rest"}, "/q/1/2/v", "/q/*rest", "rest=1/2/v"},
+		{"no match", []string{"/a/:x/b", "/a/c/d"}, "/a/c/e", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRouter()
+			for _, route := range tt.routes {
+				route := route
+				r.GET(route, func(ctx *HttpContext) {
+					var params []string
+					for _, p := range ctx.Params {
+						params = append(params, p.Key+"="+p.Value)
+					}
+					sort.Strings(params)
+					ctx.W.Write([]byte(route + " [" + strings.Join(params, ",") + "]"))
+				})
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+			want := tt.want + " [" + tt.params + "]"
+			if tt.want == "" {
+				if w.Code != http.StatusNotFound {
+					t.Errorf("GET %s = %d %q, want 404", tt.path, w.Code, w.Body.String())
+				}
+				return
+			}
+			if w.Body.String() != want {
+				t.Errorf("GET %s = %q, want %q", tt.path, w.Body.String(), want)
+			}
+		})
+	}
+}
+
+func TestOptionalSegments(t *testing.T) {
+	r := NewRouter()
+	r.GET("/report/:year?/{month:int(1,12)}?", func(ctx *HttpContext) {
+		ctx.WriteString("report " + ctx.Param("year") + "-" + ctx.Param("month"))
+	}, WithName("report"))
+
+	for path, want := range map[string]string{
+		"/report":         "report -",
+		"/report/2024":    "report 2024-",
+		"/report/2024/02": "report 2024-02",
+	} {
+		if code, body := serveBody(r, "GET", path); code != 200 || body != want {
+			t.Errorf("GET %s = %d %q, want %q", path, code, body, want)
+		}
+	}
+	if code, _ := serveBody(r, "GET", "/report/2024/13"); code != 404 {
+		t.Errorf("GET /report/2024/13 = %d, want 404", code)
+	}
+	if n := len(r.Routes()); n != 1 {
+		t.Errorf("optional route listed %d times, want once", n)
+	}
+
+	for _, tt := range []struct {
+		params map[string]string
+		want   string
+	}{
+		{nil, "/report"},
+		{map[string]string{"year": "2024"}, "/report/2024"},
+		{map[string]string{"year": "2024", "month": "2"}, "/report/2024/2"},
+		{map[string]string{"month": "2"}, "/report"},
+	} {
+		if got, err := r.URL("report", tt.params); err != nil || got != tt.want {
+			t.Errorf("URL(report, %v) = %q, %v, want %q", tt.params, got, err, tt.want)
+		}
+	}
+
+	if !r.RemoveRoute("GET", "/report/:year?/{month:int(1,12)}?") {
+		t.Fatal("RemoveRoute did not find the optional route")
+	}
+	for _, path := range []string{"/report", "/report/2024", "/report/2024/02"} {
+		if code, _ := serveBody(r, "GET", path); code != 404 {
+			t.Errorf("GET %s after RemoveRoute = %d, want 404", path, code)
+		}
+	}
+}
+
+func TestInvalidOptionalSegmentsPanic(t *testing.T) {
+	for _, path := range []string{"/report/:year?/summary", "/report/:year?/:month", "/report/summary?"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("registering %s did not panic", path)
+				}
+			}()
+			NewRouter().GET(path, func(ctx *HttpContext) {})
+		}()
+	}
+}
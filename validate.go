@@ -0,0 +1,517 @@
+package invoke
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ValidationField is the field a validator checks.
+type ValidationField struct {
+	Value  reflect.Value // Field value, with pointers dereferenced.
+	Param  string        // Rule parameter, e.g., "3" for min=3 or "" for email.
+	Parent reflect.Value // Struct holding the field, for cross-field rules.
+}
+
+// ValidatorFunc checks a field for a validate rule and returns an error
+// describing the failure, e.g., "must be at least 3", or nil if it is valid.
+type ValidatorFunc func(f ValidationField) error
+
+// Validatable is implemented by structs with rules spanning several fields.
+// Validate runs its Validate method after the field rules passed.
+type Validatable interface {
+	Validate() error
+}
+
+// ValidationError lists every field that failed validation.
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+// Error returns the failing fields, separated by semicolons.
+func (e *ValidationError) Error() string {
+	return "validation failed: " + fieldErrorsString(e.Fields)
+}
+
+var (
+	validatorsMu sync.RWMutex
+	validators   = map[string]ValidatorFunc{
+		"min":      sizeValidator("at least", func(n, limit float64) bool { return n >= limit }),
+		"max":      sizeValidator("at most", func(n, limit float64) bool { return n <= limit }),
+		"len":      sizeValidator("exactly", func(n, limit float64) bool { return n == limit }),
+		"email":    emailValidator,
+		"oneof":    oneOfValidator,
+		"regex":    regexValidator,
+		"eqfield":  fieldValidator("equal", func(c int) bool { return c == 0 }),
+		"nefield":  fieldValidator("not equal", func(c int) bool { return c != 0 }),
+		"gtfield":  fieldValidator("be greater than", func(c int) bool { return c > 0 }),
+		"gtefield": fieldValidator("be at least", func(c int) bool { return c >= 0 }),
+		"ltfield":  fieldValidator("be less than", func(c int) bool { return c < 0 }),
+		"ltefield": fieldValidator("be at most", func(c int) bool { return c <= 0 }),
+	}
+
+	// fieldRules are the built-in rules whose parameter names a sibling field.
+	fieldRules = map[string]bool{"eqfield": true, "nefield": true, "gtfield": true, "gtefield": true, "ltfield": true, "ltefield": true}
+
+	validateTags sync.Map // Parsed validate tags by tag text.
+	regexes      sync.Map // Compiled regex rule parameters by pattern.
+	checkedTypes sync.Map // Struct types whose validate tags are valid.
+)
+
+// RegisterValidator registers a rule for use in validate tags as name or
+// name=param. Registering a built-in name replaces it.
+func RegisterValidator(name string, fn ValidatorFunc) {
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+
+	validators[name] = fn
+}
+
+// validateRule is a rule of a validate tag.
+type validateRule struct {
+	name  string
+	param string
+}
+
+// parseValidateTag splits a validate tag into its rules. A regex rule takes
+// the rest of the tag, so its pattern may contain commas.
+func parseValidateTag(tag string) []validateRule {
+	if rules, ok := validateTags.Load(tag); ok {
+		return rules.([]validateRule)
+	}
+	var rules []validateRule
+	for rest := tag; rest != ""; {
+		part := rest
+		if strings.HasPrefix(rest, "regex=") {
+			rest = ""
+		} else if i := strings.IndexByte(rest, ','); i >= 0 {
+			part, rest = rest[:i], rest[i+1:]
+		} else {
+			rest = ""
+		}
+		name, param, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if name != "" {
+			rules = append(rules, validateRule{name: name, param: param})
+		}
+	}
+	validateTags.Store(tag, rules)
+	return rules
+}
+
+// Validate checks the struct v, or the struct it points to, against the
+// validate tags of its fields, e.g.:
+//
+//	type SignUp struct {
+//	    Name     string   `json:"name" validate:"required,min=1,max=64"`
+//	    Email    string   `json:"email" validate:"required,email"`
+//	    Role     string   `json:"role" validate:"omitempty,oneof=admin user"`
+//	    Password string   `json:"password" validate:"required,min=8"`
+//	    Confirm  string   `json:"confirm" validate:"eqfield=Password"`
+//	    Code     string   `json:"code" validate:"regex=^[A-Z]{2,4}$"`
+//	    Address  *Address `json:"address"` // Nested structs are validated too.
+//	}
+//
+// Besides the rules above and those added with RegisterValidator, required
+// rejects zero values and omitempty skips the remaining rules of a zero
+// value. Structs implementing Validatable are checked last. Every failing
+// field is reported in a *ValidationError. Invalid tags, such as unknown rules,
+// are reported as a plain error before any field is checked.
+func Validate(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("validate target must be a struct or a pointer to one, got %T", v)
+	}
+	if err := checkValidateTags(rv.Type()); err != nil {
+		return err
+	}
+	if fields := validateStruct(rv, "", nil); len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// checkValidateTags reports the first invalid validate tag of the struct type
+// t or of the structs nested in it: an unknown rule, an invalid regex or a
+// cross-field rule naming no field. Valid types are remembered.
+func checkValidateTags(t reflect.Type) error {
+	return checkTypeTags(t, map[reflect.Type]bool{})
+}
+
+// checkTypeTags checks the tags of t, skipping the types in seen.
+func checkTypeTags(t reflect.Type, seen map[reflect.Type]bool) error {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType || seen[t] {
+		return nil
+	}
+	if _, ok := checkedTypes.Load(t); ok {
+		return nil
+	}
+	seen[t] = true
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue // Unexported field.
+		}
+		if tag := sf.Tag.Get("validate"); tag != "-" {
+			for _, rule := range parseValidateTag(tag) {
+				if err := checkRule(t, rule); err != nil {
+					return fmt.Errorf("field %s.%s: %v", t, sf.Name, err)
+				}
+			}
+		}
+		if err := checkTypeTags(sf.Type, seen); err != nil {
+			return err
+		}
+	}
+	checkedTypes.Store(t, true)
+	return nil
+}
+
+// checkRule checks a rule of a field of the struct type parent.
+func checkRule(parent reflect.Type, rule validateRule) error {
+	if rule.name == "required" || rule.name == "omitempty" {
+		return nil
+	}
+	validatorsMu.RLock()
+	_, ok := validators[rule.name]
+	validatorsMu.RUnlock()
+	switch {
+	case !ok:
+		return fmt.Errorf("unknown validation rule %q", rule.name)
+	case rule.name == "regex":
+		compiled, err := regexp.Compile(rule.param)
+		if err != nil {
+			return fmt.Errorf("invalid validation regex %q: %v", rule.param, err)
+		}
+		regexes.LoadOrStore(rule.param, compiled)
+	case fieldRules[rule.name]:
+		if _, ok := parent.FieldByName(rule.param); !ok {
+			return fmt.Errorf("rule %s refers to unknown field %q", rule.name, rule.param)
+		}
+	case rule.name == "min" || rule.name == "max" || rule.name == "len":
+		if _, err := strconv.ParseFloat(rule.param, 64); err != nil {
+			return fmt.Errorf("rule %s has an invalid limit %q", rule.name, rule.param)
+		}
+	}
+	return nil
+}
+
+// validateStruct checks the fields of the struct value and returns fields with
+// the failures appended. Prefix is the field path of the struct.
+func validateStruct(rv reflect.Value, prefix string, fields []FieldError) []FieldError {
+	rt := rv.Type()
+	before := len(fields)
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue // Unexported field.
+		}
+		name := prefix // Embedded fields are promoted.
+		if !sf.Anonymous && prefix != "" {
+			name = prefix + "." + sf.Name
+		} else if !sf.Anonymous {
+			name = sf.Name
+		}
+		fields = validateField(rv, rv.Field(i), sf, name, fields)
+	}
+
+	if len(fields) > before {
+		return fields
+	}
+	target := rv.Interface()
+	if rv.CanAddr() {
+		target = rv.Addr().Interface()
+	}
+	if s, ok := target.(Validatable); ok {
+		if err := s.Validate(); err != nil {
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				return append(fields, FieldError{Field: prefix, Message: err.Error()})
+			}
+			for _, f := range verr.Fields {
+				if prefix != "" {
+					f.Field = strings.TrimSuffix(prefix+"."+f.Field, ".")
+				}
+				fields = append(fields, f)
+			}
+		}
+	}
+	return fields
+}
+
+// validateField checks a field against its rules, then validates its nested structs.
+func validateField(parent, fv reflect.Value, sf reflect.StructField, name string, fields []FieldError) []FieldError {
+	tag := sf.Tag.Get("validate")
+	if tag == "-" {
+		return fields
+	}
+	key := fieldKey(sf)
+	for _, rule := range parseValidateTag(tag) {
+		switch rule.name {
+		case "required":
+			if isEmpty(fv) {
+				return append(fields, FieldError{Field: name, Key: key, Rule: rule.name, Message: "is required"})
+			}
+			continue
+		case "omitempty":
+			if isEmpty(fv) {
+				return fields
+			}
+			continue
+		}
+
+		validatorsMu.RLock()
+		fn, ok := validators[rule.name]
+		validatorsMu.RUnlock()
+		if !ok {
+			continue // Rejected by checkValidateTags.
+		}
+		value := reflect.Indirect(fv)
+		if !value.IsValid() {
+			return fields // Nil pointers only fail required.
+		}
+		if err := fn(ValidationField{Value: value, Param: rule.param, Parent: parent}); err != nil {
+			return append(fields, FieldError{Field: name, Key: key, Value: valueString(value), Rule: rule.name, Message: err.Error()})
+		}
+	}
+
+	value := reflect.Indirect(fv)
+	switch {
+	case value.Kind() == reflect.Struct && value.Type() != timeType:
+		fields = validateStruct(value, name, fields)
+	case value.Kind() == reflect.Slice || value.Kind() == reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if elem := reflect.Indirect(value.Index(i)); elem.Kind() == reflect.Struct && elem.Type() != timeType {
+				fields = validateStruct(elem, name+"["+strconv.Itoa(i)+"]", fields)
+			}
+		}
+	}
+	return fields
+}
+
+// fieldKey returns the name of the field in the request: its path, query,
+// form or header key, or else its JSON name.
+func fieldKey(sf reflect.StructField) string {
+	if source, key := bindTag(sf); source != "" && source != "-" {
+		return key
+	}
+	if name := strings.Split(sf.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+		return name
+	}
+	return ""
+}
+
+// isEmpty reports whether the value is zero, or an empty slice or map.
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// valueString formats scalar values for error reports.
+func valueString(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Struct:
+		if t, ok := v.Interface().(time.Time); ok {
+			return t.Format(time.RFC3339)
+		}
+		return ""
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Interface, reflect.Func, reflect.Chan:
+		return ""
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// sizeValidator checks the length of strings, slices and maps, or the value
+// of numbers, against the rule parameter.
+func sizeValidator(desc string, ok func(n, limit float64) bool) ValidatorFunc {
+	return func(f ValidationField) error {
+		limit, err := strconv.ParseFloat(f.Param, 64)
+		if err != nil {
+			return fmt.Errorf("has an invalid limit %q", f.Param)
+		}
+		v := f.Value
+		switch v.Kind() {
+		case reflect.String:
+			if !ok(float64(utf8.RuneCountInString(v.String())), limit) {
+				return fmt.Errorf("must be %s %s characters long", desc, f.Param)
+			}
+		case reflect.Slice, reflect.Array, reflect.Map:
+			if !ok(float64(v.Len()), limit) {
+				return fmt.Errorf("must have %s %s items", desc, f.Param)
+			}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if !ok(float64(v.Int()), limit) {
+				return fmt.Errorf("must be %s %s", desc, f.Param)
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if !ok(float64(v.Uint()), limit) {
+				return fmt.Errorf("must be %s %s", desc, f.Param)
+			}
+		case reflect.Float32, reflect.Float64:
+			if !ok(v.Float(), limit) {
+				return fmt.Errorf("must be %s %s", desc, f.Param)
+			}
+		default:
+			return fmt.Errorf("cannot be checked for size")
+		}
+		return nil
+	}
+}
+
+// emailValidator checks that strings are plain email addresses.
+func emailValidator(f ValidationField) error {
+	if f.Value.Kind() == reflect.String {
+		addr, err := mail.ParseAddress(f.Value.String())
+		if err == nil && addr.Address == f.Value.String() {
+			return nil
+		}
+	}
+	return errors.New("must be a valid email address")
+}
+
+// oneOfValidator checks that values are one of the space-separated parameter words.
+func oneOfValidator(f ValidationField) error {
+	value := valueString(f.Value)
+	options := strings.Fields(f.Param)
+	for _, option := range options {
+		if value == option {
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of: %s", strings.Join(options, ", "))
+}
+
+// regexValidator checks that strings match the regular expression parameter.
+func regexValidator(f ValidationField) error {
+	re, ok := regexes.Load(f.Param)
+	if !ok {
+		compiled, err := regexp.Compile(f.Param)
+		if err != nil {
+			return fmt.Errorf("has an invalid pattern %s", f.Param)
+		}
+		re, _ = regexes.LoadOrStore(f.Param, compiled)
+	}
+	if f.Value.Kind() != reflect.String || !re.(*regexp.Regexp).MatchString(f.Value.String()) {
+		return fmt.Errorf("must match %s", f.Param)
+	}
+	return nil
+}
+
+// fieldValidator compares values with the sibling field named by the parameter.
+func fieldValidator(desc string, ok func(c int) bool) ValidatorFunc {
+	return func(f ValidationField) error {
+		other := f.Parent.FieldByName(f.Param)
+		if !other.IsValid() {
+			return fmt.Errorf("refers to unknown field %s", f.Param)
+		}
+		other = reflect.Indirect(other)
+		if !other.IsValid() {
+			return fmt.Errorf("must %s %s, which is not set", desc, f.Param)
+		}
+		c, comparable := compareValues(f.Value, other)
+		if !comparable {
+			return fmt.Errorf("cannot be compared with %s", f.Param)
+		}
+		if !ok(c) {
+			return fmt.Errorf("must %s %s", desc, f.Param)
+		}
+		return nil
+	}
+}
+
+// compareValues returns -1, 0 or 1 as a is less than, equal to or greater
+// than b, and reports whether the values are comparable.
+func compareValues(a, b reflect.Value) (int, bool) {
+	if ta, ok := a.Interface().(time.Time); ok {
+		if tb, ok := b.Interface().(time.Time); ok {
+			switch {
+			case ta.Before(tb):
+				return -1, true
+			case ta.After(tb):
+				return 1, true
+			}
+			return 0, true
+		}
+		return 0, false
+	}
+	switch {
+	case a.Kind() == reflect.String && b.Kind() == reflect.String:
+		return strings.Compare(a.String(), b.String()), true
+	case a.Kind() == reflect.Bool && b.Kind() == reflect.Bool:
+		if a.Bool() == b.Bool() {
+			return 0, true
+		}
+		return 1, true
+	}
+	x, okA := numberValue(a)
+	y, okB := numberValue(b)
+	if !okA || !okB {
+		return 0, false
+	}
+	switch {
+	case x < y:
+		return -1, true
+	case x > y:
+		return 1, true
+	}
+	return 0, true
+}
+
+// numberValue returns numeric values as a float64.
+func numberValue(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// BindAndValidate binds the request into the struct v points to and
+// validates it. If either fails, it writes the failing fields with
+// WriteErrorJSON and the ParamError code and returns false.
+func (ctx *HttpContext) BindAndValidate(v interface{}) bool {
+	err := ctx.Bind(v)
+	if err == nil {
+		err = Validate(v)
+	}
+	if err == nil {
+		return true
+	}
+	ctx.writeParamError(err)
+	return false
+}
+
+// writeParamError writes the fields of a *BindError or *ValidationError, or
+// the message of any other error, with the ParamError code.
+func (ctx *HttpContext) writeParamError(err error) {
+	var bindErr *BindError
+	var validationErr *ValidationError
+	switch {
+	case errors.As(err, &bindErr):
+		ctx.WriteErrorJSON(ParamError, bindErr.Fields)
+	case errors.As(err, &validationErr):
+		ctx.WriteErrorJSON(ParamError, validationErr.Fields)
+	default:
+		ctx.WriteErrorJSON(ParamError, err.Error())
+	}
+}
@@ -0,0 +1,61 @@
+package invoke
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type typoRequest struct {
+	Name string `query:"name" validate:"requird"`
+}
+
+type nestedTypoRequest struct {
+	Inner struct {
+		Code string `validate:"regex=^[A-Z"`
+	}
+}
+
+type unknownFieldRequest struct {
+	Confirm string `validate:"eqfield=Pasword"`
+}
+
+type badLimitRequest struct {
+	Tags []string `validate:"max=10"`
+	Name string   `validate:"min=abc"`
+}
+
+type missingLimitRequest struct {
+	Code string `validate:"len"`
+}
+
+func TestInvalidValidateTags(t *testing.T) {
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{&typoRequest{}, `unknown validation rule "requird"`},
+		{&nestedTypoRequest{}, "invalid validation regex"},
+		{&unknownFieldRequest{}, `refers to unknown field "Pasword"`},
+		{&badLimitRequest{}, `rule min has an invalid limit "abc"`},
+		{&missingLimitRequest{}, `rule len has an invalid limit ""`},
+	}
+	for _, tt := range tests {
+		err := Validate(tt.v)
+		var verr *ValidationError
+		if err == nil || errors.As(err, &verr) || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Validate(%T) = %v, want an error containing %q", tt.v, err, tt.want)
+		}
+	}
+}
+
+func TestTypedHandlerRejectsInvalidTags(t *testing.T) {
+	defer func() {
+		msg := fmt.Sprint(recover())
+		if !strings.Contains(msg, `unknown validation rule "requird"`) {
+			t.Fatalf("registration panic = %s", msg)
+		}
+	}()
+	NewRouter().GET("/x", func(ctx *HttpContext, in typoRequest) error { return nil })
+}